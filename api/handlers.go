@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -535,15 +536,10 @@ func getContainerJSON(c *context, w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// POST /containers/create
-func postContainersCreate(c *context, w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		httpError(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+// decodeContainerConfig reads a container create body.
+func decodeContainerConfig(r io.Reader) (cluster.ContainerConfig, error) {
 	var (
 		defaultMemorySwappiness = int64(-1)
-		name                    = r.Form.Get("name")
 		config                  = cluster.ContainerConfig{
 			HostConfig: containertypes.HostConfig{
 				Resources: containertypes.Resources{
@@ -561,14 +557,27 @@ func postContainersCreate(c *context, w http.ResponseWriter, r *http.Request) {
 		CPUSet:          "",
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&oldconfig); err != nil {
-		httpError(w, err.Error(), http.StatusBadRequest)
-		return
+	if err := json.NewDecoder(r).Decode(&oldconfig); err != nil {
+		return config, err
 	}
 
 	// make sure HostConfig fields are consolidated before creating container
 	cluster.ConsolidateResourceFields(&oldconfig)
-	config = oldconfig.ContainerConfig
+	return oldconfig.ContainerConfig, nil
+}
+
+// POST /containers/create
+func postContainersCreate(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := r.Form.Get("name")
+	config, err := decodeContainerConfig(r.Body)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Pass auth information along if present
 	var authConfig *apitypes.AuthConfig
@@ -595,7 +604,7 @@ func postContainersCreate(c *context, w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "{%q:%q}", "Id", container.ID)
+	json.NewEncoder(w).Encode(apitypes.ContainerCreateResponse{ID: container.ID, Warnings: container.Warnings})
 	return
 }
 
@@ -954,6 +963,151 @@ func deleteVolumes(c *context, w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// POST /swarm/nodes/{name:.*}/maintenance
+func postNodeMaintenance(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	// enter maintenance unless explicitly asked to exit it
+	enable := r.Form.Get("enable") == "" || boolValue(r, "enable")
+
+	if err := c.cluster.EngineMaintenance(name, enable, boolValue(r, "drain")); err != nil {
+		if strings.HasPrefix(err.Error(), "No such node") {
+			httpError(w, err.Error(), http.StatusNotFound)
+		} else {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /swarm/rebalance
+func postRebalance(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := boolValue(r, "dry-run")
+	migrations, err := c.cluster.Rebalance(dryRun)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "Rebalancing is already in progress") {
+			httpError(w, err.Error(), http.StatusConflict)
+		} else {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !dryRun {
+		// migrations are carried out in the background
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(migrations)
+}
+
+// POST /swarm/schedule/explain
+func postScheduleExplain(c *context, w http.ResponseWriter, r *http.Request) {
+	config, err := decodeContainerConfig(r.Body)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	containerConfig := cluster.BuildContainerConfig(config.Config, config.HostConfig, config.NetworkingConfig)
+	if err := containerConfig.Validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := c.cluster.ExplainSchedule(containerConfig)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
+
+// POST /swarm/groups
+func postContainerGroup(c *context, w http.ResponseWriter, r *http.Request) {
+	var group struct {
+		Containers []struct {
+			Name   string
+			Config json.RawMessage
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(group.Containers) == 0 {
+		httpError(w, "a group needs at least one container", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		configs = make([]*cluster.ContainerConfig, 0, len(group.Containers))
+		names   = make([]string, 0, len(group.Containers))
+	)
+	for _, container := range group.Containers {
+		config, err := decodeContainerConfig(bytes.NewReader(container.Config))
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		containerConfig := cluster.BuildContainerConfig(config.Config, config.HostConfig, config.NetworkingConfig)
+		if err := containerConfig.Validate(); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		configs = append(configs, containerConfig)
+		names = append(names, container.Name)
+	}
+
+	// Pass auth information along if present
+	var authConfig *apitypes.AuthConfig
+	buf, err := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+	if err == nil {
+		authConfig = &apitypes.AuthConfig{}
+		json.Unmarshal(buf, authConfig)
+	}
+
+	containers, err := c.cluster.CreateContainerGroup(configs, names, authConfig)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "Conflict") {
+			httpError(w, err.Error(), http.StatusConflict)
+		} else {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	type createdContainer struct {
+		ID   string `json:"Id"`
+		Name string
+		Node string
+	}
+	created := make([]createdContainer, 0, len(containers))
+	for i, container := range containers {
+		created = append(created, createdContainer{
+			ID:   container.ID,
+			Name: names[i],
+			Node: container.Engine.Name,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
 // GET /_ping
 func ping(c *context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte{'O', 'K'})
@@ -83,6 +83,10 @@ var routes = map[string]map[string]handler{
 		"/networks/{networkid:.*}/connect":    proxyNetworkConnect,
 		"/networks/{networkid:.*}/disconnect": proxyNetworkDisconnect,
 		"/volumes/create":                     postVolumesCreate,
+		"/swarm/nodes/{name:.*}/maintenance":  postNodeMaintenance,
+		"/swarm/rebalance":                    postRebalance,
+		"/swarm/schedule/explain":             postScheduleExplain,
+		"/swarm/groups":                       postContainerGroup,
 	},
 	"PUT": {
 		"/containers/{name:.*}/archive": proxyContainer,
@@ -39,5 +39,13 @@ var (
 			Flags:     []cli.Flag{flJoinAdvertise, flHeartBeat, flTTL, flJoinRandomDelay, flDiscoveryOpt},
 			Action:    join,
 		},
+		{
+			Name:  "maintenance",
+			Usage: "Put a node of the cluster into or out of maintenance",
+			Flags: []cli.Flag{
+				flMaintenanceHost, flMaintenanceDrain, flMaintenanceExit,
+				flTLS, flTLSCaCert, flTLSCert, flTLSKey, flTLSVerify},
+			Action: maintenance,
+		},
 	}
 )
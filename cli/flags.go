@@ -112,18 +112,18 @@ var (
 	}
 	flStrategy = cli.StringFlag{
 		Name:  "strategy",
-		Usage: "placement strategy to use [" + strings.Join(strategy.List(), ", ") + "]",
+		Usage: "placement strategy to use [" + strings.Join(strategy.List(), ", ") + "], spread|binpack,imagelocality=<points per GiB> or " + strategy.ExternalPrefix + "<url>",
 		Value: strategy.List()[0],
 	}
 
 	// hack for go vet
-	flFilterValue = cli.StringSlice(filter.List())
+	flFilterValue = cli.StringSlice(filter.Defaults())
 	// DefaultFilterNumber is exported
 	DefaultFilterNumber = len(flFilterValue)
 
 	flFilter = cli.StringSliceFlag{
 		Name:  "filter, f",
-		Usage: "filter to use [" + strings.Join(filter.List(), ", ") + "]",
+		Usage: "filter to use [" + strings.Join(filter.List(), ", ") + "] or " + filter.ExternalPrefix + "<name>=<url>",
 		Value: &flFilterValue,
 	}
 
@@ -151,4 +151,18 @@ var (
 		Value: "20s",
 		Usage: "Leader lock release time on failure",
 	}
+	flMaintenanceHost = cli.StringFlag{
+		Name:   "host, H",
+		Value:  "tcp://127.0.0.1:2375",
+		Usage:  "address of the swarm manager",
+		EnvVar: "SWARM_HOST",
+	}
+	flMaintenanceDrain = cli.BoolFlag{
+		Name:  "drain",
+		Usage: "move containers with a reschedule policy to other nodes",
+	}
+	flMaintenanceExit = cli.BoolFlag{
+		Name:  "exit",
+		Usage: "bring the node back from maintenance",
+	}
 )
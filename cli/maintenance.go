@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+func maintenance(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		log.Fatalf("node required to change its maintenance state. See '%s maintenance --help'.", c.App.Name)
+	}
+	node := c.Args()[0]
+
+	var (
+		tlsConfig *tls.Config
+		err       error
+		scheme    = "http"
+	)
+	if c.Bool("tls") || c.Bool("tlsverify") {
+		if !c.IsSet("tlscert") || !c.IsSet("tlskey") {
+			log.Fatal("--tlscert and --tlskey must be provided when using --tls")
+		}
+		if c.Bool("tlsverify") && !c.IsSet("tlscacert") {
+			log.Fatal("--tlscacert must be provided when using --tlsverify")
+		}
+		tlsConfig, err = loadTLSConfig(
+			c.String("tlscacert"),
+			c.String("tlscert"),
+			c.String("tlskey"),
+			c.Bool("tlsverify"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		scheme = "https"
+	}
+
+	addr := c.String("host")
+	if parts := strings.SplitN(addr, "://", 2); len(parts) == 2 {
+		addr = parts[1]
+	}
+	if !checkAddrFormat(addr) {
+		log.Fatal("--host should be of the form [tcp://]ip:port or [tcp://]hostname:port")
+	}
+
+	query := url.Values{}
+	query.Set("enable", strconv.FormatBool(!c.Bool("exit")))
+	query.Set("drain", strconv.FormatBool(c.Bool("drain")))
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     addr,
+		Path:     "/swarm/nodes/" + node + "/maintenance",
+		RawQuery: query.Encode(),
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Post(u.String(), "text/plain", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Fatalf("Error response from manager: %s", strings.TrimSpace(string(body)))
+	}
+	fmt.Println(node)
+}
@@ -163,6 +163,12 @@ func setupReplication(c *cli.Context, cluster cluster.Cluster, server *api.Serve
 	server.SetHandler(primary)
 }
 
+// maintenanceReloader is implemented by the clusters that persist their
+// engines under maintenance, to be reloaded when leadership is acquired.
+type maintenanceReloader interface {
+	ReloadMaintenance() error
+}
+
 func run(cl cluster.Cluster, candidate *leadership.Candidate, server *api.Server, primary *mux.Router, replica *api.Replica) {
 	electedCh, errCh := candidate.RunForElection()
 	var watchdog *cluster.Watchdog
@@ -171,6 +177,11 @@ func run(cl cluster.Cluster, candidate *leadership.Candidate, server *api.Server
 		case isElected := <-electedCh:
 			if isElected {
 				log.Info("Leader Election: Cluster leadership acquired")
+				if m, ok := cl.(maintenanceReloader); ok {
+					if err := m.ReloadMaintenance(); err != nil {
+						log.Errorf("Unable to reload engines under maintenance: %v", err)
+					}
+				}
 				watchdog = cluster.NewWatchdog(cl)
 				server.SetHandler(primary)
 			} else {
@@ -0,0 +1,112 @@
+package cluster
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/docker/engine-api/types"
+)
+
+// The engine labels advertising the platform and capabilities of an engine.
+// The platform labels are namespaced so that they don't replace the labels of
+// the daemons. The architecture is in the form used by images (ex. amd64). The
+// drivers and runtimes are comma separated lists (ex.
+// volumedrivers=local,rexray).
+const (
+	OSLabel             = "platform.os"
+	ArchitectureLabel   = "platform.architecture"
+	VolumeDriversLabel  = "volumedrivers"
+	NetworkDriversLabel = "networkdrivers"
+	LogDriversLabel     = "logdrivers"
+	RuntimesLabel       = "runtimes"
+)
+
+// builtinLogDrivers are the log drivers compiled in the engine, by OS type.
+// Engines don't report them in their plugins, so the log drivers of an engine
+// are only advertised when its OS type is known.
+var builtinLogDrivers = map[string][]string{
+	"linux":   {"awslogs", "fluentd", "gcplogs", "gelf", "journald", "json-file", "logentries", "none", "splunk", "syslog"},
+	"windows": {"etwlogs", "json-file", "none"},
+}
+
+// builtinCapabilities are the drivers and runtimes compiled in the engines of
+// every OS type, by label. The log drivers depend on the OS type, see
+// builtinLogDrivers.
+var builtinCapabilities = map[string][]string{
+	VolumeDriversLabel:  {"local"},
+	NetworkDriversLabel: {"bridge", "host", "ipvlan", "l2bridge", "l2tunnel", "macvlan", "nat", "null", "overlay", "transparent"},
+	RuntimesLabel:       {"runc"},
+}
+
+// capabilityLabels returns the engine labels advertising the OS, architecture,
+// plugins and runtimes of an engine.
+func capabilityLabels(info types.Info) map[string]string {
+	labels := map[string]string{}
+	if info.OSType != "" {
+		labels[OSLabel] = info.OSType
+	}
+	if info.Architecture != "" {
+		labels[ArchitectureLabel] = NormalizeArchitecture(info.Architecture)
+	}
+
+	var logDrivers []string
+	if builtins, ok := builtinLogDrivers[info.OSType]; ok {
+		logDrivers = append(append(logDrivers, builtins...), info.LoggingDriver)
+	}
+	runtimes := []string{}
+	for runtime := range info.Runtimes {
+		runtimes = append(runtimes, runtime)
+	}
+
+	for label, values := range map[string][]string{
+		VolumeDriversLabel:  info.Plugins.Volume,
+		NetworkDriversLabel: info.Plugins.Network,
+		LogDriversLabel:     logDrivers,
+		RuntimesLabel:       runtimes,
+	} {
+		if list := labelList(values); list != "" {
+			labels[label] = list
+		}
+	}
+	return labels
+}
+
+// labelList returns the sorted, deduplicated values joined by commas.
+func labelList(values []string) string {
+	uniq := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			uniq = append(uniq, value)
+		}
+	}
+	sort.Strings(uniq)
+	return strings.Join(uniq, ",")
+}
+
+// IsBuiltinCapability returns true if the driver or runtime of the label is
+// compiled in the engines of every OS type, rather than provided by a plugin.
+// Log drivers are never builtin, as they depend on the OS type.
+func IsBuiltinCapability(label, value string) bool {
+	for _, builtin := range builtinCapabilities[label] {
+		if builtin == value {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCapability returns true if the comma separated list of the label
+// contains the value.
+func HasCapability(labels map[string]string, label, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range strings.Split(labels[label], ",") {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
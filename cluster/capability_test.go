@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityLabels(t *testing.T) {
+	labels := capabilityLabels(types.Info{
+		Architecture:  "x86_64",
+		OSType:        "windows",
+		LoggingDriver: "json-file",
+		Plugins: types.PluginsInfo{
+			Volume:  []string{"rexray", "local"},
+			Network: []string{"null", "bridge", "weave", "bridge"},
+		},
+		Runtimes: map[string]types.Runtime{"runc": {}, "nvidia": {Path: "nvidia-container-runtime"}},
+	})
+	assert.Equal(t, labels, map[string]string{
+		OSLabel:             "windows",
+		ArchitectureLabel:   "amd64",
+		VolumeDriversLabel:  "local,rexray",
+		NetworkDriversLabel: "bridge,null,weave",
+		LogDriversLabel:     "etwlogs,json-file,none",
+		RuntimesLabel:       "nvidia,runc",
+	})
+
+	assert.True(t, HasCapability(labels, VolumeDriversLabel, "rexray"))
+	assert.False(t, HasCapability(labels, VolumeDriversLabel, "rex"))
+	assert.False(t, HasCapability(labels, LogDriversLabel, "fluentd"))
+	assert.False(t, HasCapability(map[string]string{}, RuntimesLabel, ""))
+
+	assert.True(t, IsBuiltinCapability(VolumeDriversLabel, "local"))
+	assert.False(t, IsBuiltinCapability(LogDriversLabel, "fluentd"))
+	assert.True(t, IsBuiltinCapability(RuntimesLabel, "runc"))
+	assert.False(t, IsBuiltinCapability(VolumeDriversLabel, "rexray"))
+	assert.False(t, IsBuiltinCapability(NetworkDriversLabel, "weave"))
+
+	assert.Empty(t, capabilityLabels(types.Info{}))
+
+	// The log drivers are unknown without the OS type.
+	assert.Equal(t, capabilityLabels(types.Info{OSType: "linux", LoggingDriver: "loggly"})[LogDriversLabel],
+		"awslogs,fluentd,gcplogs,gelf,journald,json-file,logentries,loggly,none,splunk,syslog")
+	assert.Empty(t, capabilityLabels(types.Info{LoggingDriver: "json-file"}))
+}
@@ -12,6 +12,9 @@ type Cluster interface {
 	// Create a container
 	CreateContainer(config *ContainerConfig, name string, authConfig *types.AuthConfig) (*Container, error)
 
+	// Create a group of containers, all of them or none
+	CreateContainerGroup(configs []*ContainerConfig, names []string, authConfig *types.AuthConfig) ([]*Container, error)
+
 	// Remove a container
 	RemoveContainer(container *Container, force, volumes bool) error
 
@@ -99,4 +102,52 @@ type Cluster interface {
 
 	// Tag an image
 	TagImage(IDOrName string, ref string, force bool) error
+
+	// Put an engine into maintenance, or bring it back if `enable` is false.
+	// `drain` moves the containers with a reschedule policy to other engines.
+	EngineMaintenance(IDOrName string, enable, drain bool) error
+
+	// Move containers to the engines preferred by the placement strategy.
+	// Returns the planned migrations, which are only carried out if
+	// `dryRun` is false.
+	Rebalance(dryRun bool) ([]*Migration, error)
+
+	// Run the scheduler for a container without creating it, and report how
+	// each engine was filtered and ranked.
+	ExplainSchedule(config *ContainerConfig) (*ScheduleExplanation, error)
+
+	// Return a channel closed once the engines found by the first lookup
+	// of the discovery were connected, or failed to.
+	Loaded() <-chan struct{}
+}
+
+// Migration describes a container moved from one engine to another by the
+// rebalancer.
+type Migration struct {
+	ID   string
+	Name string
+	From string
+	To   string
+}
+
+// ScheduleExplanation describes how the scheduler would place a container.
+type ScheduleExplanation struct {
+	Strategy string
+	// SoftConstraints is false when soft constraints and affinities had to be
+	// ignored to find a node.
+	SoftConstraints bool
+	Nodes           []*NodeExplanation
+	// Error is the error the container creation would fail with, if any.
+	Error string `json:",omitempty"`
+}
+
+// NodeExplanation describes how the scheduler handled one node. A node is
+// either rejected by a filter, or ranked by the strategy.
+type NodeExplanation struct {
+	ID     string
+	Name   string
+	Filter string `json:",omitempty"`
+	Reason string `json:",omitempty"`
+	Weight *int64 `json:",omitempty"`
+	Rank   int    `json:",omitempty"`
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/engine-api/types/container"
 	"github.com/docker/engine-api/types/network"
@@ -13,6 +15,19 @@ import (
 // SwarmLabelNamespace defines the key prefix in all custom labels
 const SwarmLabelNamespace = "com.docker.swarm"
 
+// defaultCPUPeriod is the CFS period used by the engine when none is given,
+// in microseconds.
+const defaultCPUPeriod = 100000
+
+const (
+	// defaultRescheduleMaxAttempts is the number of times Swarm tries to
+	// reschedule a container before giving up.
+	defaultRescheduleMaxAttempts = 3
+	// defaultRescheduleBackoff is the delay before the second attempt to
+	// reschedule a container. It doubles with every attempt.
+	defaultRescheduleBackoff = 10 * time.Second
+)
+
 // ContainerConfig is exported
 // TODO store affinities and constraints in their own fields
 type ContainerConfig struct {
@@ -39,6 +54,46 @@ func parseEnv(e string) (bool, string, string) {
 	return false, "", ""
 }
 
+// parseResource adds a resource request in the form name=amount (ex. gpu=1)
+// to resources, and returns false if it isn't valid.
+func parseResource(e string, resources map[string]int64) bool {
+	parts := strings.SplitN(e, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return false
+	}
+	amount, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return false
+	}
+	resources[parts[0]] += amount
+	return true
+}
+
+// ParseCpuset returns the CPUs of a cpuset (ex. 0-3,7), indexed by number.
+func ParseCpuset(cpuset string) (map[int]struct{}, error) {
+	cpus := make(map[int]struct{})
+	if cpuset == "" {
+		return cpus, nil
+	}
+	for _, part := range strings.Split(cpuset, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpuset %q", cpuset)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpuset %q", cpuset)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus[cpu] = struct{}{}
+		}
+	}
+	return cpus, nil
+}
+
 // ConsolidateResourceFields is a temporary fix to handle forward/backward compatibility between Docker <1.6 and >=1.7
 func ConsolidateResourceFields(c *OldContainerConfig) {
 	if c.Memory != c.HostConfig.Memory {
@@ -71,7 +126,14 @@ func BuildContainerConfig(c container.Config, h container.HostConfig, n network.
 	var (
 		affinities         []string
 		constraints        []string
+		tolerations        []string
 		reschedulePolicies []string
+		rescheduleOptions  = make(map[string]string)
+		mode               string
+		spreadBy           string
+		group              string
+		priority           string
+		resources          = make(map[string]int64)
 		env                []string
 	)
 
@@ -90,25 +152,49 @@ func BuildContainerConfig(c container.Config, h container.HostConfig, n network.
 		json.Unmarshal([]byte(labels), &constraints)
 	}
 
+	// parse tolerations from labels (ex. docker run --label 'com.docker.swarm.tolerations=["gpu=true:NoSchedule"]')
+	if labels, ok := c.Labels[SwarmLabelNamespace+".tolerations"]; ok {
+		json.Unmarshal([]byte(labels), &tolerations)
+	}
+
 	// parse reschedule policy from labels (ex. docker run --label 'com.docker.swarm.reschedule-policies=["on-node-failure"]')
 	if labels, ok := c.Labels[SwarmLabelNamespace+".reschedule-policies"]; ok {
 		json.Unmarshal([]byte(labels), &reschedulePolicies)
 	}
 
-	// parse affinities/constraints/reschedule policies from env (ex. docker run -e affinity:container==redis -e affinity:image==nginx -e constraint:region==us-east -e constraint:storage==ssd -e reschedule:off)
+	// parse resources from labels (ex. docker run --label 'com.docker.swarm.resources={"gpu":1}')
+	if labels, ok := c.Labels[SwarmLabelNamespace+".resources"]; ok {
+		json.Unmarshal([]byte(labels), &resources)
+	}
+
+	// parse affinities/constraints/tolerations/reschedule policies and options/mode/spread/resources/priority from env (ex. docker run -e affinity:container==redis -e affinity:image==nginx -e constraint:region==us-east -e constraint:storage==ssd -e toleration:gpu=true:NoSchedule -e reschedule:off -e reschedule-grace:30s -e reschedule-max-attempts:5 -e reschedule-backoff:10s -e mode:global -e spread:zone -e group:db -e resource:gpu=1 -e priority:10)
 	for _, e := range c.Env {
 		if ok, key, value := parseEnv(e); ok && key == "affinity" {
 			affinities = append(affinities, value)
 		} else if ok && key == "constraint" {
 			constraints = append(constraints, value)
+		} else if ok && key == "toleration" {
+			tolerations = append(tolerations, value)
 		} else if ok && key == "reschedule" {
 			reschedulePolicies = append(reschedulePolicies, value)
+		} else if ok && (key == "reschedule-grace" || key == "reschedule-max-attempts" || key == "reschedule-backoff") {
+			rescheduleOptions[key] = value
+		} else if ok && key == "mode" {
+			mode = value
+		} else if ok && key == "spread" {
+			spreadBy = value
+		} else if ok && key == "group" {
+			group = value
+		} else if ok && key == "priority" {
+			priority = value
+		} else if ok && key == "resource" && parseResource(value, resources) {
+			continue
 		} else {
 			env = append(env, e)
 		}
 	}
 
-	// remove affinities/constraints/reschedule policies from env
+	// remove affinities/constraints/tolerations/reschedule policies and options/mode/spread/resources/priority from env
 	c.Env = env
 
 	// store affinities in labels
@@ -125,6 +211,13 @@ func BuildContainerConfig(c container.Config, h container.HostConfig, n network.
 		}
 	}
 
+	// store tolerations in labels
+	if len(tolerations) > 0 {
+		if labels, err := json.Marshal(tolerations); err == nil {
+			c.Labels[SwarmLabelNamespace+".tolerations"] = string(labels)
+		}
+	}
+
 	// store reschedule policies in labels
 	if len(reschedulePolicies) > 0 {
 		if labels, err := json.Marshal(reschedulePolicies); err == nil {
@@ -132,9 +225,50 @@ func BuildContainerConfig(c container.Config, h container.HostConfig, n network.
 		}
 	}
 
+	// store reschedule options in labels (ex. docker run --label 'com.docker.swarm.reschedule-grace=30s')
+	for key, value := range rescheduleOptions {
+		c.Labels[SwarmLabelNamespace+"."+key] = value
+	}
+
+	// store scheduling mode in labels (ex. docker run --label 'com.docker.swarm.mode=global')
+	if mode != "" {
+		c.Labels[SwarmLabelNamespace+".mode"] = mode
+	}
+
+	// store topology spread in labels (ex. docker run --label 'com.docker.swarm.spread-by=zone' --label 'com.docker.swarm.group=db')
+	if spreadBy != "" {
+		c.Labels[SwarmLabelNamespace+".spread-by"] = spreadBy
+	}
+	if group != "" {
+		c.Labels[SwarmLabelNamespace+".group"] = group
+	}
+
+	// store priority in labels (ex. docker run --label 'com.docker.swarm.priority=10')
+	if priority != "" {
+		c.Labels[SwarmLabelNamespace+".priority"] = priority
+	}
+
+	// store resources in labels
+	if len(resources) > 0 {
+		if labels, err := json.Marshal(resources); err == nil {
+			c.Labels[SwarmLabelNamespace+".resources"] = string(labels)
+		}
+	}
+
 	return &ContainerConfig{c, h, n}
 }
 
+// Copy returns a copy of the config whose labels can be changed without
+// affecting the original.
+func (c *ContainerConfig) Copy() *ContainerConfig {
+	config := *c
+	config.Labels = make(map[string]string, len(c.Labels))
+	for k, v := range c.Labels {
+		config.Labels[k] = v
+	}
+	return &config
+}
+
 func (c *ContainerConfig) extractExprs(key string) []string {
 	var exprs []string
 
@@ -156,6 +290,95 @@ func (c *ContainerConfig) SetSwarmID(id string) {
 	c.Labels[SwarmLabelNamespace+".id"] = id
 }
 
+// IsGlobal returns true if one instance of the container should run on every node
+func (c *ContainerConfig) IsGlobal() bool {
+	return c.Labels[SwarmLabelNamespace+".mode"] == "global"
+}
+
+// GlobalID extracts the ID shared by all the instances of a global container.
+// May return an empty string if not set.
+func (c *ContainerConfig) GlobalID() string {
+	return c.Labels[SwarmLabelNamespace+".global-id"]
+}
+
+// SetGlobalID sets or overrides the ID shared by all the instances of a
+// global container.
+func (c *ContainerConfig) SetGlobalID(id string) {
+	c.Labels[SwarmLabelNamespace+".global-id"] = id
+}
+
+// GlobalInstanceName returns the name of the instance of a global container
+// on an engine: its name suffixed with the name of the engine.
+func GlobalInstanceName(name, engineName string) string {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return ""
+	}
+	return name + "." + engineName
+}
+
+// GlobalName returns the name a global container was created with from the
+// name of its instance on an engine.
+func GlobalName(instanceName, engineName string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(instanceName, "/"), "."+engineName)
+}
+
+// SpreadBy returns the node label the replicas of the container should be
+// spread across. May return an empty string if not set.
+func (c *ContainerConfig) SpreadBy() string {
+	return c.Labels[SwarmLabelNamespace+".spread-by"]
+}
+
+// Group returns the name of the group the container is a replica of. It
+// defaults to the image of the container.
+func (c *ContainerConfig) Group() string {
+	if group, ok := c.Labels[SwarmLabelNamespace+".group"]; ok && group != "" {
+		return group
+	}
+	return c.Image
+}
+
+// Cpus returns the number of CPUs reserved for the container, which may be
+// fractional: the CPU limit of the container, its quota divided by its period,
+// or the size of its cpuset, whichever is the smallest. For compatibility, the
+// CPU shares of the containers without a limit are read as a number of CPUs.
+func (c *ContainerConfig) Cpus() float64 {
+	var cpus float64
+	if c.HostConfig.CPUQuota > 0 {
+		period := c.HostConfig.CPUPeriod
+		if period <= 0 {
+			period = defaultCPUPeriod
+		}
+		cpus = float64(c.HostConfig.CPUQuota) / float64(period)
+	}
+	if cpuset, err := ParseCpuset(c.HostConfig.CpusetCpus); err == nil && len(cpuset) > 0 {
+		if cpus == 0 || float64(len(cpuset)) < cpus {
+			cpus = float64(len(cpuset))
+		}
+	}
+	if cpus == 0 && c.HostConfig.CPUShares > 0 {
+		cpus = float64(c.HostConfig.CPUShares)
+	}
+	return cpus
+}
+
+// Priority returns the priority of the container. Containers without a
+// priority have a priority of 0.
+func (c *ContainerConfig) Priority() int64 {
+	priority, _ := strconv.ParseInt(c.Labels[SwarmLabelNamespace+".priority"], 10, 64)
+	return priority
+}
+
+// Resources returns the amount of each countable resource requested by the
+// container (ex. {"gpu": 1}), indexed by resource name.
+func (c *ContainerConfig) Resources() map[string]int64 {
+	resources := make(map[string]int64)
+	if labels, ok := c.Labels[SwarmLabelNamespace+".resources"]; ok {
+		json.Unmarshal([]byte(labels), &resources)
+	}
+	return resources
+}
+
 // Affinities returns all the affinities from the ContainerConfig
 func (c *ContainerConfig) Affinities() []string {
 	return c.extractExprs("affinities")
@@ -166,6 +389,17 @@ func (c *ContainerConfig) Constraints() []string {
 	return c.extractExprs("constraints")
 }
 
+// Tolerations returns the valid tolerations from the ContainerConfig
+func (c *ContainerConfig) Tolerations() []Toleration {
+	tolerations := []Toleration{}
+	for _, s := range c.extractExprs("tolerations") {
+		if toleration, err := ParseToleration(s); err == nil {
+			tolerations = append(tolerations, toleration)
+		}
+	}
+	return tolerations
+}
+
 // AddAffinity to config
 func (c *ContainerConfig) AddAffinity(affinity string) error {
 	affinities := c.extractExprs("affinities")
@@ -228,21 +462,103 @@ func (c *ContainerConfig) HasReschedulePolicy(p string) bool {
 	return false
 }
 
+// RescheduleGrace returns how long Swarm waits before rescheduling the
+// container after a failure. The failure is ignored if it doesn't last.
+func (c *ContainerConfig) RescheduleGrace() time.Duration {
+	grace, _ := time.ParseDuration(c.Labels[SwarmLabelNamespace+".reschedule-grace"])
+	return grace
+}
+
+// RescheduleMaxAttempts returns the number of times Swarm tries to
+// reschedule the container before giving up.
+func (c *ContainerConfig) RescheduleMaxAttempts() int {
+	if attempts, err := strconv.Atoi(c.Labels[SwarmLabelNamespace+".reschedule-max-attempts"]); err == nil {
+		return attempts
+	}
+	return defaultRescheduleMaxAttempts
+}
+
+// RescheduleBackoff returns the delay before the second attempt to
+// reschedule the container. The delay doubles with every attempt.
+func (c *ContainerConfig) RescheduleBackoff() time.Duration {
+	if backoff, err := time.ParseDuration(c.Labels[SwarmLabelNamespace+".reschedule-backoff"]); err == nil {
+		return backoff
+	}
+	return defaultRescheduleBackoff
+}
+
 // Validate returns an error if the config isn't valid
 func (c *ContainerConfig) Validate() error {
 	//TODO: add validation for affinities and constraints
 	reschedulePolicies := c.extractExprs("reschedule-policies")
-	if len(reschedulePolicies) > 1 {
+	seen := make(map[string]struct{}, len(reschedulePolicies))
+	for _, reschedulePolicy := range reschedulePolicies {
+		switch reschedulePolicy {
+		case "off", "on-node-failure", "on-container-failure":
+		default:
+			return fmt.Errorf("invalid reschedule policy: %s", reschedulePolicy)
+		}
+		seen[reschedulePolicy] = struct{}{}
+	}
+	// on-node-failure and on-container-failure can be combined, off can't.
+	if _, ok := seen["off"]; ok && len(reschedulePolicies) > 1 {
 		return errors.New("too many reschedule policies")
-	} else if len(reschedulePolicies) == 1 {
-		valid := false
-		for _, validReschedulePolicy := range []string{"off", "on-node-failure"} {
-			if reschedulePolicies[0] == validReschedulePolicy {
-				valid = true
+	}
+	if len(seen) < len(reschedulePolicies) {
+		return errors.New("duplicate reschedule policies")
+	}
+
+	for _, key := range []string{"reschedule-grace", "reschedule-backoff"} {
+		if value, ok := c.Labels[SwarmLabelNamespace+"."+key]; ok {
+			if d, err := time.ParseDuration(value); err != nil || d < 0 {
+				return fmt.Errorf("invalid %s: %s", key, value)
 			}
 		}
-		if !valid {
-			return fmt.Errorf("invalid reschedule policy: %s", reschedulePolicies[0])
+	}
+	if value, ok := c.Labels[SwarmLabelNamespace+".reschedule-max-attempts"]; ok {
+		if attempts, err := strconv.Atoi(value); err != nil || attempts < 1 {
+			return fmt.Errorf("invalid reschedule-max-attempts: %s", value)
+		}
+	}
+
+	if _, err := ParseCpuset(c.HostConfig.CpusetCpus); err != nil {
+		return err
+	}
+
+	for _, toleration := range c.extractExprs("tolerations") {
+		if _, err := ParseToleration(toleration); err != nil {
+			return err
+		}
+	}
+
+	if priority, ok := c.Labels[SwarmLabelNamespace+".priority"]; ok {
+		if _, err := strconv.ParseInt(priority, 10, 64); err != nil {
+			return fmt.Errorf("invalid priority: %s", priority)
+		}
+	}
+
+	if labels, ok := c.Labels[SwarmLabelNamespace+".resources"]; ok {
+		resources := make(map[string]int64)
+		if err := json.Unmarshal([]byte(labels), &resources); err != nil {
+			return fmt.Errorf("invalid resources %s: %v", labels, err)
+		}
+		for name, amount := range resources {
+			if name == "" || amount <= 0 {
+				return fmt.Errorf("invalid amount %d for resource %q", amount, name)
+			}
+		}
+	}
+
+	if mode, ok := c.Labels[SwarmLabelNamespace+".mode"]; ok {
+		if mode != "global" {
+			return fmt.Errorf("invalid scheduling mode: %s", mode)
+		}
+		// a global container already runs on every node, there is nowhere to move it.
+		if c.HasReschedulePolicy("on-node-failure") {
+			return errors.New("global containers can't be rescheduled on node failure")
+		}
+		if c.HasReschedulePolicy("on-container-failure") {
+			return errors.New("global containers can't be rescheduled on container failure")
 		}
 	}
 
@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"testing"
+	"time"
 
 	"github.com/docker/engine-api/types/container"
 	"github.com/docker/engine-api/types/network"
@@ -104,3 +105,170 @@ func TestHaveNodeConstraint(t *testing.T) {
 	config = BuildContainerConfig(container.Config{Env: []string{"constraint:node==node1"}}, container.HostConfig{}, network.NetworkingConfig{})
 	assert.True(t, config.HaveNodeConstraint())
 }
+
+func TestGlobalMode(t *testing.T) {
+	config := BuildContainerConfig(container.Config{}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.False(t, config.IsGlobal())
+	assert.NoError(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Env: []string{"mode:global"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.Env)
+	assert.True(t, config.IsGlobal())
+	assert.NoError(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".mode": "global"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.True(t, config.IsGlobal())
+	assert.Empty(t, config.GlobalID())
+	config.SetGlobalID("foo")
+	assert.Equal(t, config.GlobalID(), "foo")
+
+	assert.Equal(t, GlobalInstanceName("agent", "node-1"), "agent.node-1")
+	assert.Equal(t, GlobalInstanceName("", "node-1"), "")
+	assert.Equal(t, GlobalName("/agent.node-1", "node-1"), "agent")
+
+	config = BuildContainerConfig(container.Config{Env: []string{"mode:invalid"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Env: []string{"mode:global", "reschedule:on-node-failure"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+}
+
+func TestCopy(t *testing.T) {
+	config := BuildContainerConfig(container.Config{Env: []string{"constraint:test==true"}}, container.HostConfig{}, network.NetworkingConfig{})
+	copy := config.Copy()
+	copy.SetSwarmID("foo")
+	assert.Equal(t, copy.SwarmID(), "foo")
+	assert.Empty(t, config.SwarmID())
+	assert.Equal(t, copy.Constraints(), config.Constraints())
+}
+
+func TestSpreadBy(t *testing.T) {
+	config := BuildContainerConfig(container.Config{Image: "redis"}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.SpreadBy())
+	assert.Equal(t, config.Group(), "redis")
+
+	config = BuildContainerConfig(container.Config{Image: "redis", Env: []string{"spread:zone", "group:cache"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.Env)
+	assert.Equal(t, config.SpreadBy(), "zone")
+	assert.Equal(t, config.Group(), "cache")
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".spread-by": "rack"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.SpreadBy(), "rack")
+}
+
+func TestResources(t *testing.T) {
+	config := BuildContainerConfig(container.Config{}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.Resources())
+	assert.NoError(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Env: []string{"resource:gpu=1", "resource:license.matlab=2", "resource:fpga"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.Env, []string{"resource:fpga"})
+	assert.Equal(t, config.Resources(), map[string]int64{"gpu": 1, "license.matlab": 2})
+	assert.NoError(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".resources": `{"gpu":2}`}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.Resources(), map[string]int64{"gpu": 2})
+	assert.NoError(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Env: []string{"resource:gpu=0"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".resources": `["gpu"]`}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+}
+
+func TestParseCpuset(t *testing.T) {
+	cpus, err := ParseCpuset("")
+	assert.NoError(t, err)
+	assert.Empty(t, cpus)
+
+	cpus, err = ParseCpuset("0-2,5")
+	assert.NoError(t, err)
+	assert.Equal(t, cpus, map[int]struct{}{0: {}, 1: {}, 2: {}, 5: {}})
+
+	for _, cpuset := range []string{"a", "1-", "3-1", "-1", "0,,1"} {
+		_, err = ParseCpuset(cpuset)
+		assert.Error(t, err, cpuset)
+	}
+}
+
+func TestCpus(t *testing.T) {
+	config := BuildContainerConfig(container.Config{}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), float64(0))
+
+	// The limit takes precedence over the shares, which are only read as a
+	// number of CPUs without a limit.
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUShares: 2, CPUQuota: 50000}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), 0.5)
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUShares: 2, CpusetCpus: "0"}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), float64(1))
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUShares: 2}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), float64(2))
+
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUQuota: 50000}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), 0.5)
+
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUQuota: 150000, CPUPeriod: 50000}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), float64(3))
+
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CpusetCpus: "0-3"}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), float64(4))
+
+	// The smallest limit wins.
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUQuota: 150000, CpusetCpus: "0-3"}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), 1.5)
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CPUQuota: 500000, CpusetCpus: "0,1"}}, network.NetworkingConfig{})
+	assert.Equal(t, config.Cpus(), float64(2))
+
+	config = BuildContainerConfig(container.Config{}, container.HostConfig{Resources: container.Resources{CpusetCpus: "0-"}}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+}
+
+func TestPriority(t *testing.T) {
+	config := BuildContainerConfig(container.Config{}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.Priority(), int64(0))
+
+	config = BuildContainerConfig(container.Config{Env: []string{"priority:10"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.Env)
+	assert.Equal(t, config.Priority(), int64(10))
+	assert.NoError(t, config.Validate())
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".priority": "-5"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.Priority(), int64(-5))
+
+	config = BuildContainerConfig(container.Config{Env: []string{"priority:high"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+}
+
+func TestReschedule(t *testing.T) {
+	config := BuildContainerConfig(container.Config{}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.RescheduleGrace(), time.Duration(0))
+	assert.Equal(t, config.RescheduleMaxAttempts(), defaultRescheduleMaxAttempts)
+	assert.Equal(t, config.RescheduleBackoff(), defaultRescheduleBackoff)
+
+	config = BuildContainerConfig(container.Config{Env: []string{"reschedule:on-node-failure", "reschedule:on-container-failure", "reschedule-grace:30s", "reschedule-max-attempts:5", "reschedule-backoff:1m"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.Env)
+	assert.NoError(t, config.Validate())
+	assert.True(t, config.HasReschedulePolicy("on-node-failure"))
+	assert.True(t, config.HasReschedulePolicy("on-container-failure"))
+	assert.Equal(t, config.RescheduleGrace(), 30*time.Second)
+	assert.Equal(t, config.RescheduleMaxAttempts(), 5)
+	assert.Equal(t, config.RescheduleBackoff(), time.Minute)
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".reschedule-grace": "10s"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, config.RescheduleGrace(), 10*time.Second)
+
+	for _, env := range [][]string{
+		{"reschedule:always"},
+		{"reschedule:off", "reschedule:on-node-failure"},
+		{"reschedule:on-node-failure", "reschedule:on-node-failure"},
+		{"reschedule-grace:soon"},
+		{"reschedule-backoff:-1s"},
+		{"reschedule-max-attempts:0"},
+		{"mode:global", "reschedule:on-container-failure"},
+	} {
+		config = BuildContainerConfig(container.Config{Env: env}, container.HostConfig{}, network.NetworkingConfig{})
+		assert.Error(t, config.Validate(), "%v", env)
+	}
+}
@@ -17,6 +17,9 @@ type Container struct {
 	Config *ContainerConfig
 	Info   types.ContainerJSON
 	Engine *Engine
+
+	// Warnings are returned to the client creating the container.
+	Warnings []string
 }
 
 // StateString returns a single string to describe state
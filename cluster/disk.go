@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// driverSizeRegexp matches the sizes in the storage driver status, which are
+// formatted with decimal units (ex. 107.4 GB).
+var driverSizeRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kKMGTP]?)B$`)
+
+// DiskStatus is the space of the storage driver of an engine, in bytes. Only
+// some drivers report it (ex. devicemapper), the fields are 0 otherwise.
+type DiskStatus struct {
+	DataAvailable     int64
+	DataTotal         int64
+	MetadataAvailable int64
+	MetadataTotal     int64
+}
+
+// parseDiskStatus parses the storage driver status from docker info.
+func parseDiskStatus(driverStatus [][2]string) DiskStatus {
+	disk := DiskStatus{}
+	for _, kv := range driverStatus {
+		var field *int64
+		switch kv[0] {
+		case "Data Space Available":
+			field = &disk.DataAvailable
+		case "Data Space Total":
+			field = &disk.DataTotal
+		case "Metadata Space Available":
+			field = &disk.MetadataAvailable
+		case "Metadata Space Total":
+			field = &disk.MetadataTotal
+		default:
+			continue
+		}
+		if size, ok := parseDriverSize(kv[1]); ok {
+			*field = size
+		}
+	}
+	return disk
+}
+
+func parseDriverSize(s string) (int64, bool) {
+	matches := driverSizeRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return 0, false
+	}
+	size, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	if matches[2] != "" {
+		for i := 0; i <= strings.Index("KMGTP", strings.ToUpper(matches[2])); i++ {
+			size *= 1000
+		}
+	}
+	return int64(size), true
+}
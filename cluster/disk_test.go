@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiskStatus(t *testing.T) {
+	disk := parseDiskStatus([][2]string{
+		{"Pool Name", "docker-8:1-1234-pool"},
+		{"Data Space Used", "11.8 GB"},
+		{"Data Space Total", "107.4 GB"},
+		{"Data Space Available", "95.6 GB"},
+		{"Metadata Space Used", "1.2 MB"},
+		{"Metadata Space Total", "2.147 GB"},
+		{"Metadata Space Available", "invalid"},
+	})
+	assert.Equal(t, disk, DiskStatus{
+		DataAvailable: 95600000000,
+		DataTotal:     107400000000,
+		MetadataTotal: 2147000000,
+	})
+
+	assert.Equal(t, parseDiskStatus([][2]string{{"Root Dir", "/var/lib/docker/aufs"}}), DiskStatus{})
+}
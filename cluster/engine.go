@@ -12,6 +12,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +27,7 @@ import (
 	"github.com/docker/engine-api/types/events"
 	"github.com/docker/engine-api/types/filters"
 	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/go-units"
 	engineapinop "github.com/docker/swarm/api/nopclient"
 	"github.com/docker/swarm/swarmclient"
 	"github.com/samalba/dockerclient"
@@ -40,6 +43,20 @@ const (
 
 	// Minimum docker engine version supported by swarm.
 	minSupportedVersion = version.Version("1.8.0")
+
+	// ResourceLabelPrefix prefixes the engine labels advertising countable
+	// resources (ex. resource.gpu=4).
+	ResourceLabelPrefix = "resource."
+
+	// OvercommitLabel is the engine label overriding the overcommit ratio of
+	// the cluster for the node (ex. swarm.overcommit=0.5).
+	OvercommitLabel = "swarm.overcommit"
+	// ReservedMemoryLabel is the engine label setting the memory kept for
+	// the system on the node (ex. swarm.reserved.memory=2g).
+	ReservedMemoryLabel = "swarm.reserved.memory"
+	// ReservedCpusLabel is the engine label setting the CPUs kept for the
+	// system on the node (ex. swarm.reserved.cpus=1).
+	ReservedCpusLabel = "swarm.reserved.cpus"
 )
 
 type engineState int
@@ -53,11 +70,6 @@ const (
 	stateHealthy
 	// disconnected means engine is removed from discovery
 	stateDisconnected
-
-	// TODO: add maintenance state. Proposal #1486
-	// maintenance means an engine is under maintenance.
-	// There is no action to migrate a node into maintenance state yet.
-	//stateMaintenance
 )
 
 var stateText = map[engineState]string{
@@ -65,7 +77,6 @@ var stateText = map[engineState]string{
 	stateUnhealthy:    "Unhealthy",
 	stateHealthy:      "Healthy",
 	stateDisconnected: "Disconnected",
-	//stateMaintenance: "Maintenance",
 }
 
 // delayer offers a simple API to random delay within a given time range.
@@ -118,21 +129,27 @@ type Engine struct {
 	Labels  map[string]string
 	Version string
 
-	stopCh          chan struct{}
-	refreshDelayer  *delayer
-	containers      map[string]*Container
-	images          []*Image
-	networks        map[string]*Network
-	volumes         map[string]*Volume
-	httpClient      *http.Client
-	url             *url.URL
-	client          dockerclient.Client
-	apiClient       swarmclient.SwarmAPIClient
-	eventHandler    EventHandler
-	state           engineState
-	lastError       string
-	updatedAt       time.Time
-	failureCount    int
+	stopCh         chan struct{}
+	refreshDelayer *delayer
+	containers     map[string]*Container
+	images         []*Image
+	disk           DiskStatus
+	networks       map[string]*Network
+	volumes        map[string]*Volume
+	httpClient     *http.Client
+	url            *url.URL
+	client         dockerclient.Client
+	apiClient      swarmclient.SwarmAPIClient
+	eventHandler   EventHandler
+	state          engineState
+	lastError      string
+	updatedAt      time.Time
+	failureCount   int
+	generation     uint64
+	// maintenance is set while the engine is under maintenance: it is still
+	// refreshed but doesn't accept new containers. It survives the engine
+	// failing and coming back.
+	maintenance     bool
 	overcommitRatio int64
 	opts            *EngineOpts
 	eventsMonitor   *EventsMonitor
@@ -272,6 +289,7 @@ func (e *Engine) Disconnect() {
 	e.client = nopclient.NewNopClient()
 	e.apiClient = engineapinop.NewNopClient()
 	e.state = stateDisconnected
+	e.generation++
 	e.emitEvent("engine_disconnect")
 }
 
@@ -297,23 +315,95 @@ func (e *Engine) IsHealthy() bool {
 	return e.state == stateHealthy
 }
 
+// IsSchedulable returns true if the engine is healthy and not under
+// maintenance, so that new containers can be created on it.
+func (e *Engine) IsSchedulable() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.state == stateHealthy && !e.maintenance
+}
+
+// IsUnderMaintenance returns true if the engine is under maintenance
+func (e *Engine) IsUnderMaintenance() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.maintenance
+}
+
+// RestoreMaintenance puts the engine under maintenance whatever its state,
+// for the engines recorded as such before they are validated.
+func (e *Engine) RestoreMaintenance() {
+	e.Lock()
+	defer e.Unlock()
+	e.maintenance = true
+	e.generation++
+}
+
+// EnterMaintenance puts a healthy engine under maintenance, so that no new
+// containers are scheduled on it. If drain is true, an engine_drain event is
+// emitted to have the containers with a reschedule policy moved away.
+func (e *Engine) EnterMaintenance(drain bool) error {
+	e.Lock()
+	switch {
+	case e.maintenance:
+		e.Unlock()
+	case e.state == stateHealthy:
+		e.maintenance = true
+		e.generation++
+		e.Unlock()
+		e.emitEvent("engine_maintenance_enter")
+	default:
+		state := stateText[e.state]
+		e.Unlock()
+		return fmt.Errorf("engine %s is %s, only healthy engines can enter maintenance", e.Name, strings.ToLower(state))
+	}
+
+	if drain {
+		e.emitEvent("engine_drain")
+	}
+	return nil
+}
+
+// ExitMaintenance moves an engine under maintenance back to the healthy state.
+func (e *Engine) ExitMaintenance() error {
+	e.Lock()
+	if !e.maintenance {
+		e.Unlock()
+		return fmt.Errorf("engine %s is not under maintenance", e.Name)
+	}
+	e.maintenance = false
+	e.generation++
+	e.Unlock()
+	e.emitEvent("engine_maintenance_exit")
+	return nil
+}
+
 // HealthIndicator returns degree of healthiness between 0 and 100.
-// 0 means node is not healthy (unhealthy, pending), 100 means last connectivity was successful
+// 0 means node is not healthy (unhealthy, pending, maintenance), 100 means last connectivity was successful
 // other values indicate recent failures but haven't moved engine out of healthy state
 func (e *Engine) HealthIndicator() int64 {
 	e.RLock()
 	defer e.RUnlock()
-	if e.state != stateHealthy || e.failureCount >= e.opts.FailureRetry {
+	if e.state != stateHealthy || e.maintenance || e.failureCount >= e.opts.FailureRetry {
 		return 0
 	}
 	return int64(100 - e.failureCount*100/e.opts.FailureRetry)
 }
 
+// Generation returns a counter that is increased whenever the engine state
+// used for scheduling (status, specs, containers or images) changes.
+func (e *Engine) Generation() uint64 {
+	e.RLock()
+	defer e.RUnlock()
+	return e.generation
+}
+
 // setState sets engine state
 func (e *Engine) setState(state engineState) {
 	e.Lock()
 	defer e.Unlock()
 	e.state = state
+	e.generation++
 }
 
 // TimeToValidate returns true if a pending node is up for validation
@@ -345,6 +435,7 @@ func (e *Engine) ValidationComplete() {
 	}
 	e.state = stateHealthy
 	e.failureCount = 0
+	e.generation++
 	go e.refreshLoop()
 }
 
@@ -368,10 +459,14 @@ func (e *Engine) HandleIDConflict(otherAddr string) {
 	e.setErrMsg(fmt.Sprintf("ID duplicated. %s shared by this node %s and another node %s", e.ID, e.Addr, otherAddr))
 }
 
-// Status returns the health status of the Engine: Healthy or Unhealthy
+// Status returns the health status of the Engine: Healthy, Maintenance or
+// Unhealthy
 func (e *Engine) Status() string {
 	e.RLock()
 	defer e.RUnlock()
+	if e.state == stateHealthy && e.maintenance {
+		return "Maintenance"
+	}
 	return stateText[e.state]
 }
 
@@ -380,6 +475,7 @@ func (e *Engine) incFailureCount() {
 	e.Lock()
 	defer e.Unlock()
 	e.failureCount++
+	e.generation++
 	if e.state == stateHealthy && e.failureCount >= e.opts.FailureRetry {
 		e.state = stateUnhealthy
 		log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Errorf("Flagging engine as unhealthy. Connect failed %d times", e.failureCount)
@@ -404,7 +500,11 @@ func (e *Engine) UpdatedAt() time.Time {
 func (e *Engine) resetFailureCount() {
 	e.Lock()
 	defer e.Unlock()
+	if e.failureCount == 0 {
+		return
+	}
 	e.failureCount = 0
+	e.generation++
 }
 
 // CheckConnectionErr checks error from client response and adjusts engine healthy indicators
@@ -414,8 +514,9 @@ func (e *Engine) CheckConnectionErr(err error) {
 		// If current state is unhealthy, change it to healthy
 		if e.state == stateUnhealthy {
 			log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Infof("Engine came back to life after %d retries. Hooray!", e.getFailureCount())
-			e.emitEvent("engine_reconnect")
+			// An engine under maintenance comes back under maintenance.
 			e.setState(stateHealthy)
+			e.emitEvent("engine_reconnect")
 		}
 		e.resetFailureCount()
 		return
@@ -500,6 +601,7 @@ func (e *Engine) updateSpecs() error {
 		e.ID = info.ID
 	} else if e.ID != info.ID {
 		e.state = statePending
+		e.generation++
 		message := fmt.Sprintf("Engine (ID: %s, Addr: %s) shows up with another ID:%s. Please remove it from cluster, it can be added back.", e.ID, e.Addr, info.ID)
 		e.lastError = message
 		return fmt.Errorf(message)
@@ -533,6 +635,8 @@ func (e *Engine) updateSpecs() error {
 	e.Name = info.Name
 	e.Cpus = int64(info.NCPU)
 	e.Memory = info.MemTotal
+	e.disk = parseDiskStatus(info.DriverStatus)
+	e.generation++
 
 	e.Labels = map[string]string{}
 	if info.Driver != "" {
@@ -547,6 +651,12 @@ func (e *Engine) updateSpecs() error {
 	if info.OperatingSystem != "" {
 		e.Labels["operatingsystem"] = info.OperatingSystem
 	}
+	if e.Version != "" {
+		e.Labels["engineversion"] = e.Version
+	}
+	for key, value := range capabilityLabels(info) {
+		e.Labels[key] = value
+	}
 	for _, label := range info.Labels {
 		kv := strings.SplitN(label, "=", 2)
 		if len(kv) != 2 {
@@ -564,6 +674,25 @@ func (e *Engine) updateSpecs() error {
 			continue
 		}
 
+		if strings.HasPrefix(kv[0], ResourceLabelPrefix) {
+			if amount, err := strconv.ParseInt(kv[1], 10, 64); err != nil || amount < 0 {
+				log.Warnf("Engine (ID: %s, Addr: %s) contains a resource label (%s) whose value isn't a positive integer, and it will be ignored.", e.ID, e.Addr, label)
+				continue
+			}
+		}
+
+		if strings.HasPrefix(kv[0], TaintLabelPrefix) {
+			if _, err := ParseTaint(strings.TrimPrefix(kv[0], TaintLabelPrefix), kv[1]); err != nil {
+				log.Warnf("Engine (ID: %s, Addr: %s) contains an invalid taint label (%s): %v, and it will be ignored.", e.ID, e.Addr, label, err)
+				continue
+			}
+		}
+
+		if err := validateCapacityLabel(kv[0], kv[1]); err != nil {
+			log.Warnf("Engine (ID: %s, Addr: %s) contains a label (%s) whose value isn't valid (%v), and it will be ignored.", e.ID, e.Addr, label, err)
+			continue
+		}
+
 		if value, exist := e.Labels[kv[0]]; exist {
 			log.Warnf("Node (ID: %s, Addr: %s) already contains a label (%s) with key (%s), and Engine's label (%s) cannot override it.", e.ID, e.Addr, value, kv[0], kv[1])
 		} else {
@@ -603,6 +732,7 @@ func (e *Engine) RemoveNetwork(network *Network) error {
 func (e *Engine) DeleteNetwork(network *Network) {
 	e.Lock()
 	delete(e.networks, network.ID)
+	e.generation++
 	e.Unlock()
 }
 
@@ -613,6 +743,7 @@ func (e *Engine) AddNetwork(network *Network) {
 		NetworkResource: network.NetworkResource,
 		Engine:          e,
 	}
+	e.generation++
 	e.Unlock()
 }
 
@@ -629,6 +760,7 @@ func (e *Engine) RemoveVolume(name string) error {
 	e.Lock()
 	defer e.Unlock()
 	delete(e.volumes, name)
+	e.generation++
 
 	return nil
 }
@@ -642,9 +774,12 @@ func (e *Engine) RefreshImages() error {
 		return err
 	}
 	e.Lock()
-	e.images = nil
-	for _, image := range images {
-		e.images = append(e.images, &Image{Image: image, Engine: e})
+	if !sameImages(e.images, images) {
+		e.images = nil
+		for _, image := range images {
+			e.images = append(e.images, &Image{Image: image, Engine: e})
+		}
+		e.generation++
 	}
 	e.Unlock()
 	return nil
@@ -658,6 +793,7 @@ func (e *Engine) refreshNetwork(ID string) error {
 		if strings.Contains(err.Error(), "No such network") {
 			e.Lock()
 			delete(e.networks, ID)
+			e.generation++
 			e.Unlock()
 			return nil
 		}
@@ -666,6 +802,7 @@ func (e *Engine) refreshNetwork(ID string) error {
 
 	e.Lock()
 	e.networks[ID] = &Network{NetworkResource: network, Engine: e}
+	e.generation++
 	e.Unlock()
 
 	return nil
@@ -680,9 +817,12 @@ func (e *Engine) RefreshNetworks() error {
 		return err
 	}
 	e.Lock()
-	e.networks = make(map[string]*Network)
-	for _, network := range networks {
-		e.networks[network.ID] = &Network{NetworkResource: network, Engine: e}
+	if !sameNetworks(e.networks, networks) {
+		e.networks = make(map[string]*Network)
+		for _, network := range networks {
+			e.networks[network.ID] = &Network{NetworkResource: network, Engine: e}
+		}
+		e.generation++
 	}
 	e.Unlock()
 	return nil
@@ -696,9 +836,12 @@ func (e *Engine) RefreshVolumes() error {
 		return err
 	}
 	e.Lock()
-	e.volumes = make(map[string]*Volume)
-	for _, volume := range volumesLsRsp.Volumes {
-		e.volumes[volume.Name] = &Volume{Volume: *volume, Engine: e}
+	if !sameVolumes(e.volumes, volumesLsRsp.Volumes) {
+		e.volumes = make(map[string]*Volume)
+		for _, volume := range volumesLsRsp.Volumes {
+			e.volumes[volume.Name] = &Volume{Volume: *volume, Engine: e}
+		}
+		e.generation++
 	}
 	e.Unlock()
 	return nil
@@ -712,6 +855,7 @@ func (e *Engine) refreshVolume(IDOrName string) error {
 		if strings.Contains(err.Error(), "No such volume") {
 			e.Lock()
 			delete(e.volumes, IDOrName)
+			e.generation++
 			e.Unlock()
 			return nil
 		} else {
@@ -721,6 +865,7 @@ func (e *Engine) refreshVolume(IDOrName string) error {
 
 	e.Lock()
 	e.volumes[volume.Name] = &Volume{Volume: volume, Engine: e}
+	e.generation++
 	e.Unlock()
 
 	return nil
@@ -752,6 +897,18 @@ func (e *Engine) RefreshContainers(full bool) error {
 
 	e.Lock()
 	defer e.Unlock()
+	// The containers which changed were counted by updateContainer, only
+	// count the removed ones.
+	if len(merged) != len(e.containers) {
+		e.generation++
+	} else {
+		for ID := range e.containers {
+			if _, ok := merged[ID]; !ok {
+				e.generation++
+				break
+			}
+		}
+	}
 	e.containers = merged
 
 	return nil
@@ -783,6 +940,7 @@ func (e *Engine) refreshContainer(ID string, full bool) (*Container, error) {
 		// The container doesn't exist on the engine, remove it.
 		e.Lock()
 		delete(e.containers, ID)
+		e.generation++
 		e.Unlock()
 
 		return nil, nil
@@ -853,6 +1011,9 @@ func (e *Engine) updateContainer(c types.Container, containers map[string]*Conta
 
 	// Update its internal state.
 	e.Lock()
+	if full || !reflect.DeepEqual(container.Container, c) {
+		e.generation++
+	}
 	container.Container = c
 	containers[container.ID] = container
 	e.Unlock()
@@ -860,6 +1021,49 @@ func (e *Engine) updateContainer(c types.Container, containers map[string]*Conta
 	return containers, nil
 }
 
+// sameImages returns true if the images listed by the engine are the ones
+// already known, so that a refresh changing nothing doesn't bump the
+// generation.
+func sameImages(current []*Image, images []types.Image) bool {
+	if len(current) != len(images) {
+		return false
+	}
+	for i, image := range images {
+		if !reflect.DeepEqual(current[i].Image, image) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameNetworks returns true if the networks listed by the engine are the ones
+// already known.
+func sameNetworks(current map[string]*Network, networks []types.NetworkResource) bool {
+	if len(current) != len(networks) {
+		return false
+	}
+	for _, network := range networks {
+		if n, ok := current[network.ID]; !ok || !reflect.DeepEqual(n.NetworkResource, network) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameVolumes returns true if the volumes listed by the engine are the ones
+// already known.
+func sameVolumes(current map[string]*Volume, volumes []*types.Volume) bool {
+	if len(current) != len(volumes) {
+		return false
+	}
+	for _, volume := range volumes {
+		if v, ok := current[volume.Name]; !ok || !reflect.DeepEqual(v.Volume, *volume) {
+			return false
+		}
+	}
+	return true
+}
+
 // refreshLoop periodically triggers engine refresh.
 func (e *Engine) refreshLoop() {
 	const maxBackoffFactor int = 1000
@@ -948,11 +1152,49 @@ func (e *Engine) UsedMemory() int64 {
 }
 
 // UsedCpus returns the sum of CPUs reserved by containers.
-func (e *Engine) UsedCpus() int64 {
-	var r int64
+func (e *Engine) UsedCpus() float64 {
+	var r float64
+	e.RLock()
+	for _, c := range e.containers {
+		r += c.Config.Cpus()
+	}
+	e.RUnlock()
+	return r
+}
+
+// DiskStatus returns the space of the storage driver of the engine.
+func (e *Engine) DiskStatus() DiskStatus {
+	e.RLock()
+	defer e.RUnlock()
+	return e.disk
+}
+
+// UsedResources returns the amount of each countable resource reserved by
+// containers, indexed by resource name.
+func (e *Engine) UsedResources() map[string]int64 {
+	r := make(map[string]int64)
 	e.RLock()
 	for _, c := range e.containers {
-		r += c.Config.HostConfig.CPUShares
+		for name, amount := range c.Config.Resources() {
+			r[name] += amount
+		}
+	}
+	e.RUnlock()
+	return r
+}
+
+// TotalResources returns the amount of each countable resource advertised by
+// the engine labels (ex. resource.gpu=4), indexed by resource name.
+func (e *Engine) TotalResources() map[string]int64 {
+	r := make(map[string]int64)
+	e.RLock()
+	for k, v := range e.Labels {
+		if !strings.HasPrefix(k, ResourceLabelPrefix) {
+			continue
+		}
+		if amount, err := strconv.ParseInt(v, 10, 64); err == nil {
+			r[strings.TrimPrefix(k, ResourceLabelPrefix)] = amount
+		}
 	}
 	e.RUnlock()
 	return r
@@ -960,12 +1202,96 @@ func (e *Engine) UsedCpus() int64 {
 
 // TotalMemory returns the total memory + overcommit
 func (e *Engine) TotalMemory() int64 {
-	return e.Memory + (e.Memory * e.overcommitRatio / 100)
+	e.RLock()
+	memory, overcommit := e.Memory, e.overcommit()
+	reserved, err := parseReservedMemory(e.Labels[ReservedMemoryLabel])
+	e.RUnlock()
+
+	if err == nil {
+		memory -= reserved
+	}
+	if memory < 0 {
+		memory = 0
+	}
+	return memory + (memory * overcommit / 100)
+}
+
+// TotalCpus returns the total cpus + overcommit. It may be fractional, like
+// the cpus reserved on the engine and used by the containers.
+func (e *Engine) TotalCpus() float64 {
+	e.RLock()
+	cpus, overcommit := float64(e.Cpus), e.overcommit()
+	reserved, err := parseReservedCpus(e.Labels[ReservedCpusLabel])
+	e.RUnlock()
+
+	if err == nil {
+		cpus -= reserved
+	}
+	if cpus < 0 {
+		cpus = 0
+	}
+	return cpus + cpus*float64(overcommit)/100
+}
+
+// overcommit returns the overcommit ratio of the engine in percent. The
+// swarm.overcommit engine label overrides the ratio of the cluster. Must be
+// called with the engine lock held.
+func (e *Engine) overcommit() int64 {
+	if ratio, err := parseOvercommit(e.Labels[OvercommitLabel]); err == nil {
+		return ratio
+	}
+	return e.overcommitRatio
+}
+
+// parseOvercommit parses an overcommit ratio (ex. 0.5) into a percentage.
+func parseOvercommit(value string) (int64, error) {
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if ratio <= -1 {
+		return 0, fmt.Errorf("overcommit should be larger than -1, %s is invalid", value)
+	}
+	return int64(ratio * 100), nil
+}
+
+// parseReservedMemory parses an amount of memory (ex. 2g) into bytes.
+func parseReservedMemory(value string) (int64, error) {
+	memory, err := units.RAMInBytes(value)
+	if err != nil {
+		return 0, err
+	}
+	if memory < 0 {
+		return 0, fmt.Errorf("reserved memory can't be negative, %s is invalid", value)
+	}
+	return memory, nil
+}
+
+// parseReservedCpus parses a number of CPUs (ex. 1 or 0.5).
+func parseReservedCpus(value string) (float64, error) {
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if cpus < 0 {
+		return 0, fmt.Errorf("reserved CPUs can't be negative, %s is invalid", value)
+	}
+	return cpus, nil
 }
 
-// TotalCpus returns the total cpus + overcommit
-func (e *Engine) TotalCpus() int64 {
-	return e.Cpus + (e.Cpus * e.overcommitRatio / 100)
+// validateCapacityLabel returns an error if the value of an engine label
+// changing the capacity of the node isn't valid.
+func validateCapacityLabel(key, value string) error {
+	var err error
+	switch key {
+	case OvercommitLabel:
+		_, err = parseOvercommit(value)
+	case ReservedMemoryLabel:
+		_, err = parseReservedMemory(value)
+	case ReservedCpusLabel:
+		_, err = parseReservedCpus(value)
+	}
+	return err
 }
 
 // CreateContainer creates a new container
@@ -985,6 +1311,9 @@ func (e *Engine) CreateContainer(config *ContainerConfig, name string, pullImage
 	// FIXME remove "duplicate" lines and move this to cluster/config.go
 	dockerConfig.HostConfig.CPUShares = int64(math.Ceil(float64(config.HostConfig.CPUShares*1024) / float64(e.Cpus)))
 
+	// Named volumes may be prefixed by their node (ex. node-1/data:/data).
+	dockerConfig.HostConfig.Binds = e.localBinds(config.HostConfig.Binds)
+
 	createResp, err = e.apiClient.ContainerCreate(context.Background(), &dockerConfig.Config, &dockerConfig.HostConfig, &dockerConfig.NetworkingConfig, name)
 	e.CheckConnectionErr(err)
 	if err != nil {
@@ -1035,6 +1364,7 @@ func (e *Engine) RemoveContainer(container *Container, force, volumes bool) erro
 	e.Lock()
 	defer e.Unlock()
 	delete(e.containers, container.ID)
+	e.generation++
 
 	return nil
 }
@@ -1226,6 +1556,22 @@ func (e *Engine) Volumes() Volumes {
 	return volumes
 }
 
+// localBinds returns a copy of the binds where the named volumes prefixed by
+// the engine name or ID lose their prefix.
+func (e *Engine) localBinds(binds []string) []string {
+	if binds == nil {
+		return nil
+	}
+	local := make([]string, 0, len(binds))
+	for _, bind := range binds {
+		if node, name, ok := ParseVolumeBind(bind); ok && node != "" && (node == e.Name || node == e.ID) {
+			bind = name + strings.TrimPrefix(bind, node+"/"+name)
+		}
+		local = append(local, bind)
+	}
+	return local
+}
+
 // Image returns the image with IDOrName in the engine
 func (e *Engine) Image(IDOrName string) *Image {
 	e.RLock()
@@ -1239,6 +1585,16 @@ func (e *Engine) Image(IDOrName string) *Image {
 	return nil
 }
 
+// ImagePlatform returns the platform of an image of the engine.
+func (e *Engine) ImagePlatform(IDOrName string) (Platform, error) {
+	image, _, err := e.apiClient.ImageInspectWithRaw(context.Background(), IDOrName, false)
+	e.CheckConnectionErr(err)
+	if err != nil {
+		return Platform{}, err
+	}
+	return Platform{OS: image.Os, Architecture: NormalizeArchitecture(image.Architecture)}, nil
+}
+
 func (e *Engine) String() string {
 	return fmt.Sprintf("engine %s addr %s", e.ID, e.Addr)
 }
@@ -1311,6 +1667,7 @@ func (e *Engine) AddContainer(container *Container) error {
 		return errors.New("container already exists")
 	}
 	e.containers[container.ID] = container
+	e.generation++
 	return nil
 }
 
@@ -1320,6 +1677,7 @@ func (e *Engine) addImage(image *Image) {
 	defer e.Unlock()
 
 	e.images = append(e.images, image)
+	e.generation++
 }
 
 // removeContainer removes a container from the internal state.
@@ -1331,6 +1689,7 @@ func (e *Engine) removeContainer(container *Container) error {
 		return errors.New("container not found")
 	}
 	delete(e.containers, container.ID)
+	e.generation++
 	return nil
 }
 
@@ -1338,6 +1697,7 @@ func (e *Engine) removeContainer(container *Container) error {
 func (e *Engine) cleanupContainers() {
 	e.Lock()
 	e.containers = make(map[string]*Container)
+	e.generation++
 	e.Unlock()
 }
 
@@ -130,6 +130,39 @@ func TestHealthIndicator(t *testing.T) {
 	assert.True(t, engine.HealthIndicator() == (int64)(100-100/engine.opts.FailureRetry))
 }
 
+func TestEngineMaintenance(t *testing.T) {
+	engine := NewEngine("test", 0, engOpts)
+	assert.Error(t, engine.EnterMaintenance(false))
+
+	engine.setState(stateHealthy)
+	assert.Error(t, engine.ExitMaintenance())
+	assert.NoError(t, engine.EnterMaintenance(false))
+	assert.True(t, engine.IsUnderMaintenance())
+	assert.True(t, engine.IsHealthy())
+	assert.False(t, engine.IsSchedulable())
+	assert.True(t, engine.HealthIndicator() == 0)
+	assert.Equal(t, engine.Status(), "Maintenance")
+	// entering maintenance twice is a no-op
+	assert.NoError(t, engine.EnterMaintenance(true))
+
+	assert.NoError(t, engine.ExitMaintenance())
+	assert.False(t, engine.IsUnderMaintenance())
+	assert.True(t, engine.IsSchedulable())
+	assert.True(t, engine.HealthIndicator() == 100)
+
+	// an engine under maintenance can still fail, and comes back under
+	// maintenance
+	assert.NoError(t, engine.EnterMaintenance(false))
+	for i := 0; i < engine.opts.FailureRetry; i++ {
+		engine.incFailureCount()
+	}
+	assert.False(t, engine.IsHealthy())
+	assert.True(t, engine.IsUnderMaintenance())
+	engine.setState(stateHealthy)
+	assert.False(t, engine.IsSchedulable())
+	assert.Equal(t, engine.Status(), "Maintenance")
+}
+
 func TestEngineConnectionFailure(t *testing.T) {
 	engine := NewEngine("test", 0, engOpts)
 	assert.False(t, engine.isConnected())
@@ -192,7 +225,7 @@ func TestEngineCpusMemory(t *testing.T) {
 	assert.True(t, engine.isConnected())
 	assert.True(t, engine.IsHealthy())
 
-	assert.Equal(t, engine.UsedCpus(), int64(0))
+	assert.Equal(t, engine.UsedCpus(), float64(0))
 	assert.Equal(t, engine.UsedMemory(), int64(0))
 
 	client.Mock.AssertExpectations(t)
@@ -233,6 +266,7 @@ func TestEngineSpecs(t *testing.T) {
 
 	assert.Equal(t, engine.Labels["kernelversion"], mockInfo2.KernelVersion)
 	assert.Equal(t, engine.Labels["operatingsystem"], mockInfo2.OperatingSystem)
+	assert.Equal(t, engine.Labels["engineversion"], engine.Version)
 	assert.Equal(t, engine.Labels["foo"], "bar")
 
 	assert.NotEqual(t, engine.Labels["node"], "node1")
@@ -560,6 +594,40 @@ func TestImages(t *testing.T) {
 	assert.Equal(t, len(result), 3)
 }
 
+func TestRefreshGeneration(t *testing.T) {
+	engine := NewEngine("test", 0, engOpts)
+	engine.setState(stateUnhealthy)
+
+	images := []types.Image{{ID: "a", RepoTags: []string{"busybox:latest"}}}
+	client := mockclient.NewMockClient()
+	apiClient := engineapimock.NewMockClient()
+	apiClient.On("Info", mock.Anything).Return(mockInfo, nil)
+	apiClient.On("ServerVersion", mock.Anything).Return(mockVersion, nil)
+	apiClient.On("NetworkList", mock.Anything,
+		mock.AnythingOfType("NetworkListOptions"),
+	).Return([]types.NetworkResource{{ID: "net", Name: "bridge"}}, nil)
+	apiClient.On("VolumeList", mock.Anything,
+		mock.AnythingOfType("Args"),
+	).Return(types.VolumesListResponse{Volumes: []*types.Volume{{Name: "data"}}}, nil)
+	apiClient.On("ImageList", mock.Anything, mock.AnythingOfType("ImageListOptions")).Return(images, nil).Twice()
+	apiClient.On("ImageList", mock.Anything, mock.AnythingOfType("ImageListOptions")).Return(append(images, types.Image{ID: "b"}), nil)
+	apiClient.On("ContainerList", mock.Anything, types.ContainerListOptions{All: true, Size: false}).Return([]types.Container{}, nil)
+	apiClient.On("Events", mock.Anything, mock.AnythingOfType("EventsOptions")).Return(&nopCloser{infiniteRead{}}, nil)
+	assert.NoError(t, engine.ConnectWithClient(client, apiClient))
+
+	// Refreshing an unchanged state keeps the generation.
+	generation := engine.Generation()
+	assert.NoError(t, engine.RefreshImages())
+	assert.NoError(t, engine.RefreshNetworks())
+	assert.NoError(t, engine.RefreshVolumes())
+	assert.NoError(t, engine.RefreshContainers(false))
+	assert.Equal(t, engine.Generation(), generation)
+
+	assert.NoError(t, engine.RefreshImages())
+	assert.True(t, engine.Generation() > generation)
+	assert.Len(t, engine.Images(), 2)
+}
+
 func TestTotalMemory(t *testing.T) {
 	engine := NewEngine("test", 0.05, engOpts)
 	engine.Memory = 1024
@@ -573,11 +641,77 @@ func TestTotalMemory(t *testing.T) {
 func TestTotalCpus(t *testing.T) {
 	engine := NewEngine("test", 0.05, engOpts)
 	engine.Cpus = 2
-	assert.Equal(t, engine.TotalCpus(), int64(2+2*5/100))
+	assert.Equal(t, engine.TotalCpus(), 2+2*5/100.0)
 
 	engine = NewEngine("test", 0, engOpts)
 	engine.Cpus = 2
-	assert.Equal(t, engine.TotalCpus(), int64(2))
+	assert.Equal(t, engine.TotalCpus(), 2.0)
+}
+
+func TestEngineCapacityLabels(t *testing.T) {
+	engine := NewEngine("test", 0.05, engOpts)
+	engine.Memory = 8 * 1024 * 1024 * 1024
+	engine.Cpus = 4
+
+	// The reservations are kept out of the node, the rest is overcommitted.
+	engine.Labels = map[string]string{
+		ReservedMemoryLabel: "2g",
+		ReservedCpusLabel:   "1",
+	}
+	assert.Equal(t, engine.TotalMemory(), int64(6*1024*1024*1024+6*1024*1024*1024*5/100))
+	assert.Equal(t, engine.TotalCpus(), 3+3*5/100.0)
+
+	engine.Labels[OvercommitLabel] = "0.5"
+	assert.Equal(t, engine.TotalMemory(), int64(9*1024*1024*1024))
+	assert.Equal(t, engine.TotalCpus(), 4.5)
+
+	// Fractional reservations are kept.
+	engine.Labels[ReservedCpusLabel] = "0.5"
+	assert.Equal(t, engine.TotalCpus(), 5.25)
+
+	// Reservations larger than the node leave nothing.
+	engine.Labels = map[string]string{
+		ReservedMemoryLabel: "16g",
+		ReservedCpusLabel:   "8",
+	}
+	assert.Equal(t, engine.TotalMemory(), int64(0))
+	assert.Equal(t, engine.TotalCpus(), 0.0)
+
+	// Invalid labels are ignored.
+	engine.Labels = map[string]string{
+		OvercommitLabel:     "-2",
+		ReservedMemoryLabel: "lots",
+		ReservedCpusLabel:   "-1",
+	}
+	assert.Equal(t, engine.TotalMemory(), int64(8*1024*1024*1024+8*1024*1024*1024*5/100))
+	assert.Equal(t, engine.TotalCpus(), 4+4*5/100.0)
+	for key, value := range engine.Labels {
+		assert.Error(t, validateCapacityLabel(key, value))
+	}
+	assert.NoError(t, validateCapacityLabel(ReservedCpusLabel, "0.5"))
+	assert.NoError(t, validateCapacityLabel("storagedriver", "aufs"))
+}
+
+func TestEngineResources(t *testing.T) {
+	engine := NewEngine("test", 0, engOpts)
+	engine.Labels = map[string]string{
+		"resource.gpu":            "4",
+		"resource.license.matlab": "2",
+		"resource.fpga":           "many",
+		"storagedriver":           "aufs",
+	}
+	assert.Equal(t, engine.TotalResources(), map[string]int64{"gpu": 4, "license.matlab": 2})
+	assert.Empty(t, engine.UsedResources())
+
+	engine.AddContainer(&Container{
+		Container: types.Container{ID: "c1"},
+		Config:    BuildContainerConfig(containertypes.Config{Env: []string{"resource:gpu=1"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}),
+	})
+	engine.AddContainer(&Container{
+		Container: types.Container{ID: "c2"},
+		Config:    BuildContainerConfig(containertypes.Config{Env: []string{"resource:gpu=2", "resource:license.matlab=1"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}),
+	})
+	assert.Equal(t, engine.UsedResources(), map[string]int64{"gpu": 3, "license.matlab": 1})
 }
 
 func TestUsedCpus(t *testing.T) {
@@ -643,7 +777,7 @@ func TestUsedCpus(t *testing.T) {
 
 				engine.ConnectWithClient(client, apiClient)
 				assert.Equal(t, engine.Cpus, int64(mockInfo.NCPU))
-				assert.Equal(t, engine.UsedCpus(), cn)
+				assert.Equal(t, engine.UsedCpus(), float64(cn))
 			}
 		}
 	}
@@ -421,7 +421,7 @@ func (c *Cluster) listNodes() []*node.Node {
 	for _, s := range c.agents {
 		n := node.NewNode(s.engine)
 		n.ID = s.id
-		n.TotalCpus = int64(sumScalarResourceValue(s.offers, "cpus"))
+		n.TotalCpus = sumScalarResourceValue(s.offers, "cpus")
 		n.UsedCpus = 0
 		n.TotalMemory = int64(sumScalarResourceValue(s.offers, "mem")) * 1024 * 1024
 		n.UsedMemory = 0
@@ -685,6 +685,33 @@ func (c *Cluster) TagImage(IDOrName string, ref string, force bool) error {
 	return errNotSupported
 }
 
+// EngineMaintenance puts an engine into maintenance
+func (c *Cluster) EngineMaintenance(IDOrName string, enable, drain bool) error {
+	return errNotSupported
+}
+
+// Rebalance moves containers to the preferred engines
+func (c *Cluster) Rebalance(dryRun bool) ([]*cluster.Migration, error) {
+	return nil, errNotSupported
+}
+
+// ExplainSchedule explains how a container would be scheduled
+func (c *Cluster) ExplainSchedule(config *cluster.ContainerConfig) (*cluster.ScheduleExplanation, error) {
+	return nil, errNotSupported
+}
+
+// Loaded returns a closed channel, the slaves are loaded from the offers
+func (c *Cluster) Loaded() <-chan struct{} {
+	loaded := make(chan struct{})
+	close(loaded)
+	return loaded
+}
+
+// CreateContainerGroup creates a group of containers, all of them or none
+func (c *Cluster) CreateContainerGroup(configs []*cluster.ContainerConfig, names []string, authConfig *types.AuthConfig) ([]*cluster.Container, error) {
+	return nil, errNotSupported
+}
+
 func (c *Cluster) checkNameUniqueness(name string) bool {
 	// Abort immediately if the name is empty.
 	if len(name) == 0 {
@@ -0,0 +1,38 @@
+package cluster
+
+import "fmt"
+
+// Platform is the OS and architecture an image is built for, in the form used
+// by images and manifest lists (ex. linux/amd64). An empty field is unknown.
+type Platform struct {
+	OS           string
+	Architecture string
+}
+
+// String returns the platform in the form os/architecture.
+func (p Platform) String() string {
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
+
+// Matches returns true if the os and architecture labels of an engine match
+// the known fields of the platform.
+func (p Platform) Matches(labels map[string]string) bool {
+	return (p.OS == "" || labels[OSLabel] == p.OS) &&
+		(p.Architecture == "" || labels[ArchitectureLabel] == p.Architecture)
+}
+
+// NormalizeArchitecture returns the architecture reported by an engine (ex.
+// x86_64) in the form used by images (ex. amd64).
+func NormalizeArchitecture(architecture string) string {
+	switch architecture {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64", "armv8", "armv8l":
+		return "arm64"
+	case "armhf", "armel", "armv6l", "armv7l":
+		return "arm"
+	case "i386", "i486", "i586", "i686", "x86":
+		return "386"
+	}
+	return architecture
+}
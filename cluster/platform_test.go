@@ -0,0 +1,21 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatform(t *testing.T) {
+	assert.Equal(t, NormalizeArchitecture("x86_64"), "amd64")
+	assert.Equal(t, NormalizeArchitecture("aarch64"), "arm64")
+	assert.Equal(t, NormalizeArchitecture("armv7l"), "arm")
+	assert.Equal(t, NormalizeArchitecture("ppc64le"), "ppc64le")
+
+	labels := map[string]string{OSLabel: "linux", ArchitectureLabel: "arm64"}
+	assert.True(t, Platform{OS: "linux", Architecture: "arm64"}.Matches(labels))
+	assert.True(t, Platform{Architecture: "arm64"}.Matches(labels))
+	assert.False(t, Platform{OS: "linux", Architecture: "amd64"}.Matches(labels))
+	assert.False(t, Platform{OS: "windows"}.Matches(labels))
+	assert.Equal(t, Platform{OS: "linux", Architecture: "arm64"}.String(), "linux/arm64")
+}
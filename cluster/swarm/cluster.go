@@ -19,7 +19,9 @@ import (
 	"github.com/docker/engine-api/types"
 	containertypes "github.com/docker/engine-api/types/container"
 	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/go-connections/nat"
 	"github.com/docker/go-units"
+	"github.com/docker/libkv/store"
 	"github.com/docker/swarm/cluster"
 	"github.com/docker/swarm/scheduler"
 	"github.com/docker/swarm/scheduler/node"
@@ -40,7 +42,7 @@ func (p *pendingContainer) ToContainer() *cluster.Container {
 		Config: p.Config,
 		Info: types.ContainerJSON{
 			ContainerJSONBase: &types.ContainerJSONBase{
-				HostConfig: &containertypes.HostConfig{},
+				HostConfig: &p.Config.HostConfig,
 			},
 		},
 		Engine: p.Engine,
@@ -63,6 +65,21 @@ type Cluster struct {
 	scheduler         *scheduler.Scheduler
 	discovery         discovery.Backend
 	pendingContainers map[string]*pendingContainer
+	maintenance       *maintenanceStore
+	nodes             *nodeStore
+	registry          *registryClient
+	loaded            chan struct{}
+	reservationSeq    uint64
+	reservedAt        map[string]uint64
+
+	rebalanceLock  sync.Mutex
+	rebalance      bool
+	rebalanceDelay time.Duration
+	rebalanceTimer *time.Timer
+	rebalancing    bool
+
+	portRange  string
+	preemption bool
 
 	overcommitRatio float64
 	engineOpts      *cluster.EngineOpts
@@ -82,9 +99,12 @@ func NewCluster(scheduler *scheduler.Scheduler, TLSConfig *tls.Config, discovery
 		TLSConfig:         TLSConfig,
 		discovery:         discovery,
 		pendingContainers: make(map[string]*pendingContainer),
+		nodes:             newNodeStore(),
+		loaded:            make(chan struct{}),
 		overcommitRatio:   0.05,
 		engineOpts:        engineOptions,
 		createRetry:       0,
+		rebalanceDelay:    defaultRebalanceDelay,
 	}
 
 	if val, ok := options.Float("swarm.overcommit", ""); ok {
@@ -105,6 +125,56 @@ func NewCluster(scheduler *scheduler.Scheduler, TLSConfig *tls.Config, discovery
 		cluster.createRetry = val
 	}
 
+	if val, ok := options.Bool("swarm.rebalance", ""); ok {
+		cluster.rebalance = val
+	}
+
+	if val, ok := options.String("swarm.rebalance.delay", ""); ok {
+		delay, err := time.ParseDuration(val)
+		if err != nil || delay < 0 {
+			log.Fatalf("swarm.rebalance.delay should be a positive duration, %s is invalid", val)
+		}
+		cluster.rebalanceDelay = delay
+	}
+
+	if val, ok := options.Bool("swarm.preemption", ""); ok {
+		cluster.preemption = val
+	}
+
+	if val, ok := options.String("swarm.ports", ""); ok {
+		if first, _, err := nat.ParsePortRangeToInt(val); err != nil || first == 0 {
+			log.Fatalf("swarm.ports should be a range of ports, %s is invalid", val)
+		}
+		cluster.portRange = val
+	}
+
+	if val, ok := options.Bool("swarm.registry.platforms", ""); ok && val {
+		var insecure []string
+		if val, ok := options.String("swarm.registry.insecure", ""); ok && val != "" {
+			insecure = strings.Split(val, ",")
+		}
+		timeout := defaultRegistryTimeout
+		if val, ok := options.String("swarm.registry.timeout", ""); ok {
+			d, err := time.ParseDuration(val)
+			if err != nil || d <= 0 {
+				log.Fatalf("swarm.registry.timeout should be a positive duration, %s is invalid", val)
+			}
+			timeout = d
+		}
+		cluster.registry = newRegistryClient(insecure, timeout)
+	}
+
+	// Engines under maintenance are persisted in the KV store, if any.
+	var (
+		kv     store.Store
+		prefix string
+	)
+	if kvDiscovery, ok := discovery.(kvBackend); ok {
+		kv = kvDiscovery.Store()
+		prefix = kvDiscovery.Prefix()
+	}
+	cluster.maintenance = newMaintenanceStore(kv, prefix)
+
 	discoveryCh, errCh := cluster.discovery.Watch(nil)
 	go cluster.monitorDiscovery(discoveryCh, errCh)
 	go cluster.monitorPendingEngines()
@@ -114,6 +184,9 @@ func NewCluster(scheduler *scheduler.Scheduler, TLSConfig *tls.Config, discovery
 
 // Handle callbacks for the events
 func (c *Cluster) Handle(e *cluster.Event) error {
+	if e.From == "swarm" && e.Status == "engine_reconnect" {
+		c.triggerRebalance()
+	}
 	c.eventHandlers.Handle(e)
 	return nil
 }
@@ -140,12 +213,36 @@ func (c *Cluster) generateUniqueID() string {
 
 // StartContainer starts a container
 func (c *Cluster) StartContainer(container *cluster.Container, hostConfig *dockerclient.HostConfig) error {
-	return container.Engine.StartContainer(container.ID, hostConfig)
+	if err := container.Engine.StartContainer(container.ID, hostConfig); err != nil {
+		return err
+	}
+
+	// Starting one instance of a global container starts all of them.
+	if container.Config.IsGlobal() {
+		c.startGlobalContainers(container.Config.GlobalID())
+	}
+	return nil
 }
 
 // CreateContainer aka schedule a brand new container into the cluster.
 func (c *Cluster) CreateContainer(config *cluster.ContainerConfig, name string, authConfig *types.AuthConfig) (*cluster.Container, error) {
-	container, err := c.createContainer(config, name, false, authConfig)
+	return c.CreateContainerWithConstraints(config, name, nil, authConfig)
+}
+
+// CreateContainerWithConstraints schedules a new container with extra
+// constraints, which are not stored in the container.
+func (c *Cluster) CreateContainerWithConstraints(config *cluster.ContainerConfig, name string, extra []string, authConfig *types.AuthConfig) (*cluster.Container, error) {
+	platform, err := c.resolvePlatform(config, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	constraints := append(platform, extra...)
+
+	if config.IsGlobal() {
+		return c.createGlobalContainer(config, name, constraints, authConfig)
+	}
+
+	container, err := c.createContainer(config, name, false, constraints, authConfig)
 
 	if err != nil {
 		var retries int64
@@ -156,90 +253,90 @@ func (c *Cluster) CreateContainer(config *cluster.ContainerConfig, name string,
 			// Check if the image exists in the cluster
 			// If exists, retry with an image affinity
 			if c.Image(config.Image) != nil {
-				container, err = c.createContainer(config, name, true, authConfig)
+				container, err = c.createContainer(config, name, true, constraints, authConfig)
 				retries++
 			}
 		}
 
 		for ; retries < c.createRetry && err != nil; retries++ {
 			log.WithFields(log.Fields{"Name": "Swarm"}).Warnf("Failed to create container: %s, retrying", err)
-			container, err = c.createContainer(config, name, false, authConfig)
+			container, err = c.createContainer(config, name, false, constraints, authConfig)
 		}
 	}
 	return container, err
 }
 
-func (c *Cluster) createContainer(config *cluster.ContainerConfig, name string, withImageAffinity bool, authConfig *types.AuthConfig) (*cluster.Container, error) {
-	c.scheduler.Lock()
-
-	// Ensure the name is available
-	if !c.checkNameUniqueness(name) {
-		c.scheduler.Unlock()
-		return nil, fmt.Errorf("Conflict: The name %s is already assigned. You have to delete (or rename) that container to be able to assign %s to a container again.", name, name)
-	}
-
-	swarmID := config.SwarmID()
-	if swarmID == "" {
-		// Associate a Swarm ID to the container we are creating.
-		swarmID = c.generateUniqueID()
-		config.SetSwarmID(swarmID)
-	}
-
-	if network := c.Networks().Get(string(config.HostConfig.NetworkMode)); network != nil && network.Scope == "local" {
-		if !config.HaveNodeConstraint() {
-			config.AddConstraint("node==~" + network.Engine.Name)
-		}
-		config.HostConfig.NetworkMode = containertypes.NetworkMode(network.Name)
-	}
-
-	if withImageAffinity {
-		config.AddAffinity("image==" + config.Image)
-	}
-
-	nodes, err := c.scheduler.SelectNodesForContainer(c.listNodes(), config)
-
-	if withImageAffinity {
-		config.RemoveAffinity("image==" + config.Image)
-	}
+func (c *Cluster) createContainer(config *cluster.ContainerConfig, name string, withImageAffinity bool, constraints []string, authConfig *types.AuthConfig) (*cluster.Container, error) {
+	c.resolveLocalNetwork(config)
 
+	p, victims, err := c.reserveContainer(config, name, withImageAffinity, constraints)
 	if err != nil {
-		c.scheduler.Unlock()
 		return nil, err
 	}
-	n := nodes[0]
-	engine, ok := c.engines[n.ID]
-	if !ok {
-		c.scheduler.Unlock()
-		return nil, fmt.Errorf("error creating container")
-	}
-
-	c.pendingContainers[swarmID] = &pendingContainer{
-		Name:   name,
-		Config: config,
-		Engine: engine,
-	}
-
-	c.scheduler.Unlock()
+	config, engine, swarmID := p.Config, p.Engine, p.Config.SwarmID()
 
+	// The victims of a preemption are only evicted once the container is
+	// created, so that a failed creation leaves them untouched.
 	container, err := engine.CreateContainer(config, name, true, authConfig)
 
+	var reschedule []*cluster.Container
 	if err != nil {
-		log.WithFields(log.Fields{"NodeName": n.Name, "NodeID": n.ID}).WithError(err).Error("Failed to create container")
+		log.WithFields(log.Fields{"NodeName": engine.Name, "NodeID": engine.ID}).WithError(err).Error("Failed to create container")
 	} else {
+		if len(victims) > 0 {
+			by := name
+			if by == "" {
+				by = swarmID
+			}
+			reschedule = c.evictContainers(victims, by)
+		}
+
 		containerFlag := name
 		if containerFlag == "" {
 			containerFlag = stringid.TruncateID(container.ID)
 		}
-		log.WithFields(log.Fields{"NodeName": n.Name, "NodeID": n.ID}).Debugf("Scheduling container %s to ", containerFlag)
+		log.WithFields(log.Fields{"NodeName": engine.Name, "NodeID": engine.ID}).Debugf("Scheduling container %s to ", containerFlag)
 	}
 
 	c.scheduler.Lock()
 	delete(c.pendingContainers, swarmID)
 	c.scheduler.Unlock()
 
+	c.reschedulePreempted(reschedule)
+
 	return container, err
 }
 
+// resolveLocalNetwork pins containers using a local network to the engine
+// owning it.
+func (c *Cluster) resolveLocalNetwork(config *cluster.ContainerConfig) {
+	if network := c.Networks().Get(string(config.HostConfig.NetworkMode)); network != nil && network.Scope == "local" {
+		if !config.HaveNodeConstraint() {
+			config.AddConstraint("node==~" + network.Engine.Name)
+		}
+		config.HostConfig.NetworkMode = containertypes.NetworkMode(network.Name)
+	}
+}
+
+// ExplainSchedule runs the scheduler for a container without creating it.
+func (c *Cluster) ExplainSchedule(config *cluster.ContainerConfig) (*cluster.ScheduleExplanation, error) {
+	config = config.Copy()
+	c.resolveLocalNetwork(config)
+	platform, err := c.resolvePlatform(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	config = withConstraints(config, platform)
+
+	// Explaining doesn't reserve anything, the lock is only needed to get a
+	// consistent view of the nodes and pending containers.
+	c.scheduler.Lock()
+	nodes := c.listNodes()
+	c.scheduler.Unlock()
+
+	return c.scheduler.Explain(nodes, config), nil
+}
+
 // RemoveContainer aka Remove a container from the cluster.
 func (c *Cluster) RemoveContainer(container *cluster.Container, force, volumes bool) error {
 	return container.Engine.RemoveContainer(container, force, volumes)
@@ -288,11 +385,26 @@ func (c *Cluster) getEngineByAddr(addr string) *cluster.Engine {
 	return nil
 }
 
+func (c *Cluster) getEngineByIDOrName(IDOrName string) *cluster.Engine {
+	c.RLock()
+	defer c.RUnlock()
+
+	if engine, ok := c.engines[IDOrName]; ok {
+		return engine
+	}
+	for _, engine := range c.engines {
+		if engine.Name == IDOrName {
+			return engine
+		}
+	}
+	return nil
+}
+
 func (c *Cluster) hasEngineByAddr(addr string) bool {
 	return c.getEngineByAddr(addr) != nil
 }
 
-func (c *Cluster) addEngine(addr string) bool {
+func (c *Cluster) addEngine(addr string, validated *sync.WaitGroup) bool {
 	// Check the engine is already registered by address.
 	if c.hasEngineByAddr(addr) {
 		return false
@@ -311,7 +423,15 @@ func (c *Cluster) addEngine(addr string) bool {
 	// validatePendingEngine will start a thread to validate the engine.
 	// If the engine is reachable and valid, it'll be monitored and updated in a loop.
 	// If engine is not reachable, pending engines will be examined once in a while
-	go c.validatePendingEngine(engine)
+	if validated != nil {
+		validated.Add(1)
+	}
+	go func() {
+		c.validatePendingEngine(engine)
+		if validated != nil {
+			validated.Done()
+		}
+	}()
 
 	return true
 }
@@ -355,9 +475,15 @@ func (c *Cluster) validatePendingEngine(engine *cluster.Engine) bool {
 
 	// Engine validated, move from pendingEngines table to engines table
 	delete(c.pendingEngines, engine.Addr)
+	// Engines under maintenance are never schedulable, not even between
+	// their validation and the restore of their maintenance.
+	if c.maintenance.Has(engine.ID) {
+		engine.RestoreMaintenance()
+	}
 	// set engine state to healthy, and start refresh loop
 	engine.ValidationComplete()
 	c.engines[engine.ID] = engine
+	c.triggerRebalance()
 
 	log.Infof("Registered Engine %s at %s", engine.Name, engine.Addr)
 	return true
@@ -386,12 +512,20 @@ func (c *Cluster) removeEngine(addr string) bool {
 func (c *Cluster) monitorDiscovery(ch <-chan discovery.Entries, errCh <-chan error) {
 	// Watch changes on the discovery channel.
 	currentEntries := discovery.Entries{}
+	first := true
 	for {
 		select {
 		case entries := <-ch:
 			added, removed := currentEntries.Diff(entries)
 			currentEntries = entries
 
+			// The cluster is loaded once the engines of the first
+			// entries were validated, or failed to.
+			var validated *sync.WaitGroup
+			if first {
+				validated = &sync.WaitGroup{}
+			}
+
 			// Remove engines first. `addEngine` will refuse to add an engine
 			// if there's already an engine with the same ID.  If an engine
 			// changes address, we have to first remove it then add it back.
@@ -400,7 +534,15 @@ func (c *Cluster) monitorDiscovery(ch <-chan discovery.Entries, errCh <-chan err
 			}
 
 			for _, entry := range added {
-				c.addEngine(entry.String())
+				c.addEngine(entry.String(), validated)
+			}
+
+			if first {
+				first = false
+				go func() {
+					validated.Wait()
+					close(c.loaded)
+				}()
 			}
 		case err := <-errCh:
 			log.Errorf("Discovery error: %v", err)
@@ -408,6 +550,12 @@ func (c *Cluster) monitorDiscovery(ch <-chan discovery.Entries, errCh <-chan err
 	}
 }
 
+// Loaded returns a channel closed once the engines of the first discovery
+// entries were validated, or failed to.
+func (c *Cluster) Loaded() <-chan struct{} {
+	return c.loaded
+}
+
 // monitorPendingEngines checks if some previous unreachable/invalid engines have been fixed
 func (c *Cluster) monitorPendingEngines() {
 	const minimumValidationInterval time.Duration = 10 * time.Second
@@ -743,6 +891,12 @@ func (c *Cluster) Containers() cluster.Containers {
 }
 
 func (c *Cluster) checkNameUniqueness(name string) bool {
+	return c.checkGlobalNameUniqueness(name, "")
+}
+
+// checkGlobalNameUniqueness checks the name is not used by any container but
+// the instances of the global container globalID.
+func (c *Cluster) checkGlobalNameUniqueness(name, globalID string) bool {
 	// Abort immediately if the name is empty.
 	if len(name) == 0 {
 		return true
@@ -752,6 +906,9 @@ func (c *Cluster) checkNameUniqueness(name string) bool {
 	defer c.RUnlock()
 	for _, e := range c.engines {
 		for _, c := range e.Containers() {
+			if globalID != "" && c.Config.GlobalID() == globalID {
+				continue
+			}
 			for _, cname := range c.Names {
 				if cname == name || cname == "/"+name {
 					return false
@@ -762,6 +919,9 @@ func (c *Cluster) checkNameUniqueness(name string) bool {
 
 	// check pending containers.
 	for _, c := range c.pendingContainers {
+		if globalID != "" && c.Config.GlobalID() == globalID {
+			continue
+		}
 		if c.Name == name {
 			return false
 		}
@@ -810,15 +970,13 @@ func (c *Cluster) listNodes() []*node.Node {
 	c.RLock()
 	defer c.RUnlock()
 
-	out := make([]*node.Node, 0, len(c.engines))
-	for _, e := range c.engines {
-		node := node.NewNode(e)
+	out := c.nodes.Nodes(c.engines)
+	for _, node := range out {
 		for _, pc := range c.pendingContainers {
-			if pc.Engine.ID == e.ID && node.Container(pc.Config.SwarmID()) == nil {
+			if pc.Engine.ID == node.ID && node.Container(pc.Config.SwarmID()) == nil {
 				node.AddContainer(pc.ToContainer())
 			}
 		}
-		out = append(out, node)
 	}
 
 	return out
@@ -851,11 +1009,11 @@ func (c *Cluster) TotalMemory() int64 {
 
 // TotalCpus returns the total CPUs of the cluster
 func (c *Cluster) TotalCpus() int64 {
-	var totalCpus int64
+	var totalCpus float64
 	for _, engine := range c.engines {
 		totalCpus += engine.TotalCpus()
 	}
-	return totalCpus
+	return int64(totalCpus)
 }
 
 // Info returns some info about the cluster, like nb or containers / images
@@ -895,8 +1053,23 @@ func (c *Cluster) Info() [][2]string {
 			info = append(info, [2]string{"  └ Containers", fmt.Sprintf("%d", len(engine.Containers()))})
 		}
 
-		info = append(info, [2]string{"  └ Reserved CPUs", fmt.Sprintf("%d / %d", engine.UsedCpus(), engine.TotalCpus())})
+		info = append(info, [2]string{"  └ Reserved CPUs", fmt.Sprintf("%g / %g", engine.UsedCpus(), engine.TotalCpus())})
 		info = append(info, [2]string{"  └ Reserved Memory", fmt.Sprintf("%s / %s", units.BytesSize(float64(engine.UsedMemory())), units.BytesSize(float64(engine.TotalMemory())))})
+		if total := engine.TotalResources(); len(total) > 0 {
+			used := engine.UsedResources()
+			resources := make([]string, 0, len(total))
+			for name, amount := range total {
+				resources = append(resources, fmt.Sprintf("%s: %d / %d", name, used[name], amount))
+			}
+			sort.Strings(resources)
+			info = append(info, [2]string{"  └ Reserved Resources", strings.Join(resources, ", ")})
+		}
+		if disk := engine.DiskStatus(); disk.DataTotal > 0 {
+			info = append(info, [2]string{"  └ Data Space Available", fmt.Sprintf("%s / %s", units.HumanSize(float64(disk.DataAvailable)), units.HumanSize(float64(disk.DataTotal)))})
+		}
+		for _, warning := range c.scheduler.NodeWarnings(node.NewNode(engine)) {
+			info = append(info, [2]string{"  └ Warning", warning})
+		}
 		labels := make([]string, 0, len(engine.Labels))
 		for k, v := range engine.Labels {
 			labels = append(labels, k+"="+v)
@@ -993,3 +1166,48 @@ func (c *Cluster) TagImage(IDOrName string, ref string, force bool) error {
 
 	return err
 }
+
+// ReloadMaintenance reloads the engines under maintenance from the KV store
+// and applies them to the engines, as another manager may have changed them
+// while this one was a replica.
+func (c *Cluster) ReloadMaintenance() error {
+	if err := c.maintenance.load(); err != nil {
+		return err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+	for _, engine := range c.engines {
+		switch {
+		case c.maintenance.Has(engine.ID) && !engine.IsUnderMaintenance():
+			engine.RestoreMaintenance()
+		case !c.maintenance.Has(engine.ID) && engine.IsUnderMaintenance():
+			engine.ExitMaintenance()
+		}
+	}
+	return nil
+}
+
+// EngineMaintenance puts an engine into maintenance, or brings it back.
+func (c *Cluster) EngineMaintenance(IDOrName string, enable, drain bool) error {
+	engine := c.getEngineByIDOrName(IDOrName)
+	if engine == nil {
+		return fmt.Errorf("No such node: %s", IDOrName)
+	}
+
+	if !enable {
+		if err := c.maintenance.Remove(engine.ID); err != nil {
+			return err
+		}
+		return engine.ExitMaintenance()
+	}
+
+	if err := c.maintenance.Add(engine.ID); err != nil {
+		return err
+	}
+	if err := engine.EnterMaintenance(drain); err != nil {
+		c.maintenance.Remove(engine.ID)
+		return err
+	}
+	return nil
+}
@@ -268,3 +268,39 @@ func TestTagImage(t *testing.T) {
 	assert.Nil(t, c.TagImage("busybox", "test_busybox:latest", false))
 	assert.NotNil(t, c.TagImage("busybox_not_exists", "test_busybox:latest", false))
 }
+
+func TestCheckGlobalNameUniqueness(t *testing.T) {
+	c := &Cluster{
+		engines:           make(map[string]*cluster.Engine),
+		pendingContainers: make(map[string]*pendingContainer),
+	}
+
+	config := cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	config.SetGlobalID("global1")
+	instance := &cluster.Container{
+		Container: types.Container{
+			ID:    "instance1-id",
+			Names: []string{"/agent"},
+		},
+		Config: config,
+	}
+	other := &cluster.Container{
+		Container: types.Container{
+			ID:    "other-id",
+			Names: []string{"/other"},
+		},
+		Config: cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}),
+	}
+	n := createEngine(t, "test-engine", instance, other)
+	c.engines[n.ID] = n
+
+	assert.False(t, c.checkNameUniqueness("agent"))
+	assert.True(t, c.checkGlobalNameUniqueness("agent", "global1"))
+	assert.False(t, c.checkGlobalNameUniqueness("agent", "global2"))
+	assert.False(t, c.checkGlobalNameUniqueness("other", "global1"))
+
+	for _, node := range c.listNodes() {
+		assert.True(t, hasGlobalInstance(node, "global1"))
+		assert.False(t, hasGlobalInstance(node, "global2"))
+	}
+}
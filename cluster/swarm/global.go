@@ -0,0 +1,159 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// createGlobalContainer creates one instance of the container on every node
+// accepted by the scheduler which doesn't run one yet. All the instances share
+// the same global ID, but each has its own swarm ID and its name suffixed with
+// the name of its node. It returns the instance of the first node by name,
+// with a warning for each node where the creation failed.
+func (c *Cluster) createGlobalContainer(config *cluster.ContainerConfig, name string, constraints []string, authConfig *types.AuthConfig) (*cluster.Container, error) {
+	c.resolveLocalNetwork(config)
+
+	c.scheduler.Lock()
+
+	globalID := config.GlobalID()
+	if globalID == "" {
+		globalID = c.generateUniqueID()
+	}
+
+	nodes, err := c.scheduler.SelectNodesForContainer(c.listNodes(), withConstraints(config, constraints))
+	if err != nil {
+		c.scheduler.Unlock()
+		return nil, err
+	}
+
+	candidates := []*node.Node{}
+	for _, n := range nodes {
+		if hasGlobalInstance(n, globalID) {
+			continue
+		}
+		// Instances of the same global container are allowed to share a name.
+		if instanceName := cluster.GlobalInstanceName(name, n.Name); !c.checkGlobalNameUniqueness(instanceName, globalID) {
+			c.scheduler.Unlock()
+			return nil, fmt.Errorf("Conflict: The name %s is already assigned. You have to delete (or rename) that container to be able to assign %s to a container again.", instanceName, instanceName)
+		}
+		candidates = append(candidates, n)
+	}
+
+	pending := map[string]*pendingContainer{}
+	for _, n := range candidates {
+		c.RLock()
+		engine, ok := c.engines[n.ID]
+		c.RUnlock()
+		if !ok {
+			continue
+		}
+
+		_, bindings, err := c.selectNode([]*node.Node{n}, config)
+		if err != nil {
+			log.WithFields(log.Fields{"NodeName": n.Name, "NodeID": n.ID}).WithError(err).Warn("Skipping node for global container")
+			continue
+		}
+
+		instance := config.Copy()
+		if bindings != nil {
+			instance.HostConfig.PortBindings = bindings
+		}
+		instance.SetGlobalID(globalID)
+		instance.SetSwarmID(c.generateUniqueID())
+		pending[instance.SwarmID()] = &pendingContainer{
+			Name:   cluster.GlobalInstanceName(name, engine.Name),
+			Config: instance,
+			Engine: engine,
+		}
+		c.reserve(instance.SwarmID(), pending[instance.SwarmID()])
+	}
+
+	c.scheduler.Unlock()
+
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("global container %s already runs on every node", globalID)
+	}
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		containers []*cluster.Container
+		errs       []string
+	)
+	for _, p := range pending {
+		wg.Add(1)
+
+		go func(p *pendingContainer) {
+			defer wg.Done()
+
+			container, err := p.Engine.CreateContainer(p.Config, p.Name, true, authConfig)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.WithFields(log.Fields{"NodeName": p.Engine.Name, "NodeID": p.Engine.ID}).WithError(err).Error("Failed to create global container instance")
+				errs = append(errs, fmt.Sprintf("%s: %s", p.Engine.Name, err.Error()))
+				return
+			}
+			containers = append(containers, container)
+		}(p)
+	}
+	wg.Wait()
+
+	c.scheduler.Lock()
+	for swarmID := range pending {
+		delete(c.pendingContainers, swarmID)
+	}
+	c.scheduler.Unlock()
+
+	// Missing instances are created again when their engine reconnects, so
+	// only fail if no instance could be created at all.
+	if len(containers) == 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	first := containers[0]
+	for _, container := range containers[1:] {
+		if container.Engine.Name < first.Engine.Name {
+			first = container
+		}
+	}
+	// The warnings are only for the client, not kept in the state of the
+	// engine.
+	created := *first
+	sort.Strings(errs)
+	for _, err := range errs {
+		created.Warnings = append(created.Warnings, "failed to create the global container instance on "+err)
+	}
+	return &created, nil
+}
+
+// startGlobalContainers starts the instances of a global container which are
+// not running yet.
+func (c *Cluster) startGlobalContainers(globalID string) {
+	for _, container := range c.Containers() {
+		if container.Config.GlobalID() != globalID || (container.Info.State != nil && container.Info.State.Running) {
+			continue
+		}
+		if err := container.Engine.StartContainer(container.ID, nil); err != nil {
+			log.WithFields(log.Fields{"NodeName": container.Engine.Name, "NodeID": container.Engine.ID}).WithError(err).Errorf("Failed to start global container instance %s", container.ID)
+		}
+	}
+}
+
+// hasGlobalInstance returns true if the node runs an instance of the global container.
+func hasGlobalInstance(n *node.Node, globalID string) bool {
+	for _, container := range n.Containers {
+		if container.Config != nil && container.Config.GlobalID() == globalID {
+			return true
+		}
+	}
+	return false
+}
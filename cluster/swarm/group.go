@@ -0,0 +1,161 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+)
+
+var errGlobalInGroup = errors.New("global containers can't be part of a group")
+
+// CreateContainerGroup places every container of the group before creating
+// any of them, so that the group is either fully created or not at all. The
+// containers already created are removed if one of them can't be.
+func (c *Cluster) CreateContainerGroup(configs []*cluster.ContainerConfig, names []string, authConfig *types.AuthConfig) ([]*cluster.Container, error) {
+	if len(configs) != len(names) {
+		return nil, errors.New("each container of the group needs a name, possibly empty")
+	}
+
+	// The platforms are resolved before reserving the group, as it may
+	// query the engines and registries.
+	platforms := make([][]string, len(configs))
+	for i, config := range configs {
+		platform, err := c.resolvePlatform(config, authConfig)
+		if err != nil {
+			return nil, fmt.Errorf("unable to schedule container %d of the group: %v", i, err)
+		}
+		platforms[i] = platform
+	}
+
+	pending, err := c.reserveGroup(configs, names, platforms)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		c.scheduler.Lock()
+		for _, p := range pending {
+			delete(c.pendingContainers, p.Config.SwarmID())
+		}
+		c.scheduler.Unlock()
+	}()
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		containers = make([]*cluster.Container, len(pending))
+		errs       []string
+	)
+	for i, p := range pending {
+		wg.Add(1)
+
+		go func(i int, p *pendingContainer) {
+			defer wg.Done()
+
+			container, err := p.Engine.CreateContainer(p.Config, p.Name, true, authConfig)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.WithFields(log.Fields{"NodeName": p.Engine.Name, "NodeID": p.Engine.ID}).WithError(err).Error("Failed to create container of a group")
+				errs = append(errs, fmt.Sprintf("%s: %v", p.Engine.Name, err))
+				return
+			}
+			containers[i] = container
+		}(i, p)
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return containers, nil
+	}
+
+	// Roll back
+	for _, container := range containers {
+		if container == nil {
+			continue
+		}
+		if err := container.Engine.RemoveContainer(container, true, true); err != nil {
+			log.WithFields(log.Fields{"NodeName": container.Engine.Name, "NodeID": container.Engine.ID}).WithError(err).Errorf("Failed to remove container %s of a failed group", container.ID)
+		}
+	}
+	return nil, fmt.Errorf("unable to create the group: %s", strings.Join(errs, ", "))
+}
+
+// reserveGroup schedules every container of the group, and reserves their
+// placement as pending containers. Each container is scheduled knowing where
+// the previous ones go, so that affinities between them are honoured. Nothing
+// is reserved if one of them can't be scheduled. The platform constraints of
+// each container, if any, only apply to its scheduling.
+func (c *Cluster) reserveGroup(configs []*cluster.ContainerConfig, names []string, platforms [][]string) ([]*pendingContainer, error) {
+	c.scheduler.Lock()
+	defer c.scheduler.Unlock()
+
+	pending := []*pendingContainer{}
+	release := func() {
+		for _, p := range pending {
+			delete(c.pendingContainers, p.Config.SwarmID())
+		}
+	}
+
+	for i, config := range configs {
+		name := names[i]
+		if config.IsGlobal() {
+			release()
+			return nil, errGlobalInGroup
+		}
+
+		if !c.checkNameUniqueness(name) {
+			release()
+			return nil, fmt.Errorf("Conflict: The name %s is already assigned. You have to delete (or rename) that container to be able to assign %s to a container again.", name, name)
+		}
+
+		swarmID := config.SwarmID()
+		if swarmID == "" {
+			swarmID = c.generateUniqueID()
+			config.SetSwarmID(swarmID)
+		}
+
+		c.resolveLocalNetwork(config)
+
+		var platform []string
+		if platforms != nil {
+			platform = platforms[i]
+		}
+		nodes, err := c.scheduler.SelectNodesForContainer(c.listNodes(), withConstraints(config, platform))
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("unable to schedule container %d of the group: %v", i, err)
+		}
+		n, bindings, err := c.selectNode(nodes, config)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("unable to schedule container %d of the group: %v", i, err)
+		}
+		c.RLock()
+		engine, ok := c.engines[n.ID]
+		c.RUnlock()
+		if !ok {
+			release()
+			return nil, fmt.Errorf("error creating container %d of the group", i)
+		}
+
+		if bindings != nil {
+			config = config.Copy()
+			config.HostConfig.PortBindings = bindings
+		}
+
+		p := &pendingContainer{
+			Name:   name,
+			Config: config,
+			Engine: engine,
+		}
+		c.reserve(swarmID, p)
+		pending = append(pending, p)
+	}
+	return pending, nil
+}
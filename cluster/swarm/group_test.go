@@ -0,0 +1,90 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+func createGroupCluster(t *testing.T) *Cluster {
+	s, err := strategy.New("spread")
+	assert.NoError(t, err)
+	filters, err := filter.New([]string{"affinity", "constraint"})
+	assert.NoError(t, err)
+
+	c := &Cluster{
+		engines:           make(map[string]*cluster.Engine),
+		pendingContainers: make(map[string]*pendingContainer),
+		scheduler:         scheduler.New(s, filters),
+	}
+	for _, ID := range []string{"node-1", "node-2"} {
+		c.engines[ID] = createEngine(t, ID)
+	}
+	return c
+}
+
+func createGroupConfig(env ...string) *cluster.ContainerConfig {
+	return cluster.BuildContainerConfig(containertypes.Config{Image: "mysql", Env: env}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+}
+
+func TestReserveGroup(t *testing.T) {
+	c := createGroupCluster(t)
+
+	// The replica is kept away from the primary.
+	pending, err := c.reserveGroup([]*cluster.ContainerConfig{
+		createGroupConfig("constraint:node==node-1"),
+		createGroupConfig("affinity:container!=db-primary"),
+	}, []string{"db-primary", "db-replica"}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, pending[0].Engine.ID, "node-1")
+	assert.Equal(t, pending[1].Engine.ID, "node-2")
+	assert.Len(t, c.pendingContainers, 2)
+	assert.NotEqual(t, pending[0].Config.SwarmID(), pending[1].Config.SwarmID())
+	c.pendingContainers = make(map[string]*pendingContainer)
+
+	// Nothing is reserved if a container can't be scheduled.
+	_, err = c.reserveGroup([]*cluster.ContainerConfig{
+		createGroupConfig(),
+		createGroupConfig("constraint:node==node-3"),
+	}, []string{"db-primary", "db-replica"}, nil)
+	assert.Error(t, err)
+	assert.Empty(t, c.pendingContainers)
+
+	// Names must be unique, within the group too.
+	_, err = c.reserveGroup([]*cluster.ContainerConfig{
+		createGroupConfig(),
+		createGroupConfig(),
+	}, []string{"db", "db"}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Conflict")
+	assert.Empty(t, c.pendingContainers)
+
+	c.engines["node-1"].AddContainer(&cluster.Container{
+		Container: types.Container{ID: "existing", Names: []string{"/db"}},
+		Config:    createGroupConfig(),
+		Engine:    c.engines["node-1"],
+	})
+	_, err = c.reserveGroup([]*cluster.ContainerConfig{createGroupConfig()}, []string{"db"}, nil)
+	assert.Error(t, err)
+	assert.Empty(t, c.pendingContainers)
+
+	// The platform constraints only apply to the scheduling.
+	config := createGroupConfig()
+	pending, err = c.reserveGroup([]*cluster.ContainerConfig{config}, []string{"cache"}, [][]string{{"node==node-2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, pending[0].Engine.ID, "node-2")
+	assert.Empty(t, config.Constraints())
+	c.pendingContainers = make(map[string]*pendingContainer)
+
+	// Global containers run on every node, they can't be grouped.
+	_, err = c.reserveGroup([]*cluster.ContainerConfig{createGroupConfig("mode:global")}, []string{""}, nil)
+	assert.Equal(t, err, errGlobalInGroup)
+}
@@ -0,0 +1,106 @@
+package swarm
+
+import (
+	"path"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+// maintenancePath is where engines under maintenance are recorded, relative
+// to the discovery prefix.
+const maintenancePath = "docker/swarm/maintenance"
+
+// kvBackend is implemented by the discovery backends built on top of a KV
+// store, such as consul, etcd and zookeeper.
+type kvBackend interface {
+	Store() store.Store
+	Prefix() string
+}
+
+// maintenanceStore keeps track of the engines under maintenance. When the
+// cluster uses a KV discovery backend, the list is persisted so that it
+// survives a manager restart.
+type maintenanceStore struct {
+	sync.RWMutex
+
+	engines map[string]struct{}
+	kv      store.Store
+	path    string
+}
+
+func newMaintenanceStore(kv store.Store, prefix string) *maintenanceStore {
+	m := &maintenanceStore{
+		engines: make(map[string]struct{}),
+		kv:      kv,
+		path:    path.Join(prefix, maintenancePath),
+	}
+
+	if err := m.load(); err != nil {
+		log.Errorf("Unable to load engines under maintenance: %v", err)
+	}
+	return m
+}
+
+// load replaces the engines under maintenance with the ones recorded in the
+// KV store, which may have been changed by another manager.
+func (m *maintenanceStore) load() error {
+	if m.kv == nil {
+		return nil
+	}
+
+	pairs, err := m.kv.List(m.path)
+	if err != nil && err != store.ErrKeyNotFound {
+		return err
+	}
+	engines := make(map[string]struct{})
+	for _, pair := range pairs {
+		engines[path.Base(pair.Key)] = struct{}{}
+	}
+
+	m.Lock()
+	m.engines = engines
+	m.Unlock()
+	return nil
+}
+
+// Has returns true if the engine with the given ID is under maintenance.
+func (m *maintenanceStore) Has(ID string) bool {
+	if m == nil {
+		return false
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	_, ok := m.engines[ID]
+	return ok
+}
+
+// Add records the engine as being under maintenance.
+func (m *maintenanceStore) Add(ID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.kv != nil {
+		if err := m.kv.Put(path.Join(m.path, ID), []byte(ID), nil); err != nil {
+			return err
+		}
+	}
+	m.engines[ID] = struct{}{}
+	return nil
+}
+
+// Remove forgets about the engine being under maintenance.
+func (m *maintenanceStore) Remove(ID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.kv != nil {
+		if err := m.kv.Delete(path.Join(m.path, ID)); err != nil && err != store.ErrKeyNotFound {
+			return err
+		}
+	}
+	delete(m.engines, ID)
+	return nil
+}
@@ -0,0 +1,109 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore only implements the subset of store.Store used by the
+// maintenanceStore.
+type fakeStore struct {
+	store.Store
+	pairs map[string]*store.KVPair
+}
+
+func (s *fakeStore) Put(key string, value []byte, options *store.WriteOptions) error {
+	s.pairs[key] = &store.KVPair{Key: key, Value: value}
+	return nil
+}
+
+func (s *fakeStore) Delete(key string) error {
+	if _, ok := s.pairs[key]; !ok {
+		return store.ErrKeyNotFound
+	}
+	delete(s.pairs, key)
+	return nil
+}
+
+func (s *fakeStore) List(directory string) ([]*store.KVPair, error) {
+	pairs := []*store.KVPair{}
+	for _, pair := range s.pairs {
+		pairs = append(pairs, pair)
+	}
+	if len(pairs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	return pairs, nil
+}
+
+func TestMaintenanceStore(t *testing.T) {
+	kv := &fakeStore{pairs: make(map[string]*store.KVPair)}
+
+	m := newMaintenanceStore(kv, "prefix")
+	assert.False(t, m.Has("engine1"))
+	assert.NoError(t, m.Add("engine1"))
+	assert.True(t, m.Has("engine1"))
+	assert.Contains(t, kv.pairs, "prefix/docker/swarm/maintenance/engine1")
+
+	// engines under maintenance are restored from the store
+	m = newMaintenanceStore(kv, "prefix")
+	assert.True(t, m.Has("engine1"))
+	assert.NoError(t, m.Remove("engine1"))
+	assert.False(t, m.Has("engine1"))
+	assert.Empty(t, kv.pairs)
+
+	m = newMaintenanceStore(kv, "prefix")
+	assert.False(t, m.Has("engine1"))
+}
+
+func TestEngineMaintenance(t *testing.T) {
+	c := &Cluster{
+		engines:     make(map[string]*cluster.Engine),
+		maintenance: newMaintenanceStore(nil, ""),
+	}
+	engine := createEngine(t, "test-engine")
+	c.engines[engine.ID] = engine
+
+	assert.Error(t, c.EngineMaintenance("unknown", true, false))
+	// a pending engine can't enter maintenance
+	assert.Error(t, c.EngineMaintenance("test-engine", true, false))
+	assert.False(t, c.maintenance.Has(engine.ID))
+
+	engine.ValidationComplete()
+	assert.NoError(t, c.EngineMaintenance("test-engine", true, false))
+	assert.True(t, engine.IsUnderMaintenance())
+	assert.True(t, c.maintenance.Has(engine.ID))
+
+	assert.NoError(t, c.EngineMaintenance("test-engine", false, false))
+	assert.False(t, engine.IsUnderMaintenance())
+	assert.False(t, c.maintenance.Has(engine.ID))
+}
+
+func TestReloadMaintenance(t *testing.T) {
+	kv := &fakeStore{pairs: make(map[string]*store.KVPair)}
+	c := &Cluster{
+		engines:     make(map[string]*cluster.Engine),
+		maintenance: newMaintenanceStore(kv, "prefix"),
+	}
+	engine1 := createEngine(t, "engine1")
+	engine1.ValidationComplete()
+	c.engines[engine1.ID] = engine1
+	engine2 := createEngine(t, "engine2")
+	engine2.ValidationComplete()
+	c.engines[engine2.ID] = engine2
+	assert.NoError(t, c.EngineMaintenance("engine2", true, false))
+
+	// another manager changed the engines under maintenance
+	other := newMaintenanceStore(kv, "prefix")
+	assert.NoError(t, other.Add(engine1.ID))
+	assert.NoError(t, other.Remove(engine2.ID))
+
+	assert.NoError(t, c.ReloadMaintenance())
+	assert.True(t, c.maintenance.Has(engine1.ID))
+	assert.True(t, engine1.IsUnderMaintenance())
+	assert.False(t, c.maintenance.Has(engine2.ID))
+	assert.False(t, engine2.IsUnderMaintenance())
+}
@@ -0,0 +1,178 @@
+package swarm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+)
+
+var imageIDRegexp = regexp.MustCompile(`^(sha256:)?[a-f0-9]{12,64}$`)
+
+// resolvePlatform returns the constraints keeping a container on the nodes of
+// the OS and architecture of its image. Containers already constrained on the
+// os or architecture labels are left untouched. The constraints only apply to
+// the scheduling of the container, see withConstraints, so that the container
+// is constrained again whenever it is moved.
+func (c *Cluster) resolvePlatform(config *cluster.ContainerConfig, authConfig *types.AuthConfig) ([]string, error) {
+	if hasPlatformConstraint(config) {
+		return nil, nil
+	}
+	platforms := c.imagePlatforms(config.Image, authConfig)
+	if len(platforms) == 0 {
+		return nil, nil
+	}
+
+	c.RLock()
+	found := false
+	for _, engine := range c.engines {
+		for _, platform := range platforms {
+			if platform.Matches(engine.Labels) {
+				found = true
+			}
+		}
+	}
+	c.RUnlock()
+	if !found {
+		list := []string{}
+		for _, platform := range platforms {
+			list = append(list, platform.String())
+		}
+		return nil, fmt.Errorf("unable to find a node for the platform of image %s: %s", config.Image, strings.Join(list, ", "))
+	}
+	return platformConstraints(platforms), nil
+}
+
+// imagePlatforms returns the platforms of an image. Images referred to by name
+// are looked up in their registry, if enabled, as the manifest list of a
+// multi-platform image is the only place listing all its platforms: a local
+// copy is a single variant of the image. Images referred to by ID are a
+// single platform, read from a copy on the engines.
+func (c *Cluster) imagePlatforms(image string, authConfig *types.AuthConfig) []cluster.Platform {
+	if image == "" {
+		return nil
+	}
+	if imageIDRegexp.MatchString(image) {
+		return c.localImagePlatform(image)
+	}
+	if c.registry == nil {
+		return nil
+	}
+
+	platforms, err := c.registry.Platforms(image, authConfig)
+	if err != nil {
+		log.WithError(err).Debugf("Unable to find the platform of image %s in its registry", image)
+		return nil
+	}
+	return platforms
+}
+
+// localImagePlatform returns the platform of an image ID, from the first
+// engine having it.
+func (c *Cluster) localImagePlatform(image string) []cluster.Platform {
+	c.RLock()
+	engines := make([]*cluster.Engine, 0, len(c.engines))
+	for _, engine := range c.engines {
+		engines = append(engines, engine)
+	}
+	c.RUnlock()
+
+	for _, engine := range engines {
+		img := engine.Image(image)
+		if img == nil {
+			continue
+		}
+		platform, err := engine.ImagePlatform(img.ID)
+		if err != nil {
+			log.WithFields(log.Fields{"NodeName": engine.Name, "NodeID": engine.ID}).WithError(err).Debugf("Unable to inspect image %s", image)
+			continue
+		}
+		return []cluster.Platform{platform}
+	}
+	return nil
+}
+
+// withConstraints returns the config to schedule a container with: a copy of
+// its config with the extra constraints, or the config itself if there are
+// none.
+func withConstraints(config *cluster.ContainerConfig, constraints []string) *cluster.ContainerConfig {
+	if len(constraints) == 0 {
+		return config
+	}
+	config = config.Copy()
+	for _, constraint := range constraints {
+		config.AddConstraint(constraint)
+	}
+	return config
+}
+
+// nodePlatformConstraints returns the constraints keeping a container on the
+// platform of the engine labels of its node, for the containers moved without
+// going through resolvePlatform again.
+func nodePlatformConstraints(config *cluster.ContainerConfig, labels map[string]string) []string {
+	if hasPlatformConstraint(config) {
+		return nil
+	}
+	constraints := []string{}
+	for _, label := range []string{cluster.OSLabel, cluster.ArchitectureLabel} {
+		if value := labels[label]; value != "" {
+			constraints = append(constraints, label+"=="+value)
+		}
+	}
+	return constraints
+}
+
+// platformConstraints returns the constraints on the os and architecture
+// labels matching the platforms. For images of several OSes, any of their
+// architectures is allowed on any of their OSes.
+func platformConstraints(platforms []cluster.Platform) []string {
+	oses, architectures := map[string]bool{}, map[string]bool{}
+	for _, platform := range platforms {
+		oses[platform.OS] = true
+		architectures[platform.Architecture] = true
+	}
+
+	constraints := []string{}
+	for _, values := range []struct {
+		label  string
+		values map[string]bool
+	}{
+		{cluster.OSLabel, oses},
+		{cluster.ArchitectureLabel, architectures},
+	} {
+		// An unknown field allows any value.
+		if values.values[""] {
+			continue
+		}
+		list := []string{}
+		for value := range values.values {
+			list = append(list, value)
+		}
+		sort.Strings(list)
+		if len(list) == 1 {
+			constraints = append(constraints, values.label+"=="+list[0])
+		} else {
+			constraints = append(constraints, fmt.Sprintf("%s in (%s)", values.label, strings.Join(list, ",")))
+		}
+	}
+	return constraints
+}
+
+// hasPlatformConstraint returns true if the container is constrained on the
+// os or architecture labels.
+func hasPlatformConstraint(config *cluster.ContainerConfig) bool {
+	for _, constraint := range config.Constraints() {
+		i := strings.IndexAny(constraint, "=!<> ")
+		if i < 0 {
+			continue
+		}
+		if key := constraint[:i]; key == cluster.OSLabel || key == cluster.ArchitectureLabel {
+			return true
+		}
+	}
+	return false
+}
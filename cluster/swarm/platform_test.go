@@ -0,0 +1,132 @@
+package swarm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	engineapimock "github.com/docker/swarm/api/mockclient"
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlatformConstraints(t *testing.T) {
+	assert.Equal(t, platformConstraints([]cluster.Platform{{OS: "linux", Architecture: "arm64"}}),
+		[]string{"platform.os==linux", "platform.architecture==arm64"})
+	assert.Equal(t, platformConstraints([]cluster.Platform{{OS: "linux", Architecture: "arm64"}, {OS: "linux", Architecture: "amd64"}}),
+		[]string{"platform.os==linux", "platform.architecture in (amd64,arm64)"})
+	assert.Equal(t, platformConstraints([]cluster.Platform{{Architecture: "amd64"}}),
+		[]string{"platform.architecture==amd64"})
+}
+
+func TestResolvePlatform(t *testing.T) {
+	server, r := createRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	c := &Cluster{engines: createSnapshotEngines(2, 0), registry: r}
+	c.engines["node-0"].Labels = map[string]string{cluster.OSLabel: "linux", cluster.ArchitectureLabel: "amd64"}
+	c.engines["node-1"].Labels = map[string]string{cluster.OSLabel: "linux", cluster.ArchitectureLabel: "amd64"}
+
+	// The constraints are returned, not stored in the config.
+	config := createPreemptionConfig(1)
+	config.Image = host + "/multi"
+	constraints, err := c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, constraints, []string{"platform.os==linux", "platform.architecture in (amd64,arm64)"})
+	assert.Empty(t, config.Constraints())
+
+	// No node of the platform of the image.
+	config = createPreemptionConfig(1)
+	config.Image = host + "/single:1.0"
+	_, err = c.resolvePlatform(config, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "linux/arm64")
+
+	// Explicit platform constraints are left untouched.
+	config.AddConstraint("platform.architecture==amd64")
+	constraints, err = c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, constraints)
+
+	// Unknown images are not constrained.
+	config = createPreemptionConfig(1)
+	config.Image = host + "/missing"
+	constraints, err = c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, constraints)
+
+	// The registries are only queried if enabled.
+	c.registry = nil
+	config.Image = host + "/single:1.0"
+	constraints, err = c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, constraints)
+}
+
+func TestResolvePlatformLocalCopy(t *testing.T) {
+	server, r := createRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	imageID := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	// An engine has pulled the amd64 variant of the multi-platform image.
+	engine := cluster.NewEngine(mockInfo.ID, 0, engOpts)
+	engine.Name, engine.ID = mockInfo.ID, mockInfo.ID
+	apiClient := engineapimock.NewMockClient()
+	apiClient.On("Info", mock.Anything).Return(mockInfo, nil)
+	apiClient.On("ServerVersion", mock.Anything).Return(mockVersion, nil)
+	apiClient.On("NetworkList", mock.Anything, mock.AnythingOfType("NetworkListOptions")).Return([]types.NetworkResource{}, nil)
+	apiClient.On("VolumeList", mock.Anything, mock.Anything).Return(types.VolumesListResponse{}, nil)
+	apiClient.On("Events", mock.Anything, mock.AnythingOfType("EventsOptions")).Return(&nopCloser{bytes.NewBufferString("")}, nil)
+	apiClient.On("ImageList", mock.Anything, mock.AnythingOfType("ImageListOptions")).Return([]types.Image{{ID: imageID, RepoTags: []string{host + "/multi:latest"}}}, nil)
+	apiClient.On("ContainerList", mock.Anything, types.ContainerListOptions{All: true, Size: false}).Return([]types.Container{}, nil).Once()
+	assert.NoError(t, engine.ConnectWithClient(mockclient.NewMockClient(), apiClient))
+	engine.Labels = map[string]string{cluster.OSLabel: "linux", cluster.ArchitectureLabel: "amd64"}
+
+	c := &Cluster{engines: createSnapshotEngines(1, 0), registry: r}
+	c.engines[engine.ID] = engine
+	c.engines["node-0"].Labels = map[string]string{cluster.OSLabel: "linux", cluster.ArchitectureLabel: "arm64"}
+
+	// The local copy doesn't restrict the image to its variant, and isn't
+	// inspected.
+	config := createPreemptionConfig(1)
+	config.Image = host + "/multi"
+	constraints, err := c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, constraints, []string{"platform.os==linux", "platform.architecture in (amd64,arm64)"})
+
+	c.registry = nil
+	constraints, err = c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, constraints)
+
+	// An image ID is a single platform.
+	apiClient.On("ImageInspectWithRaw", mock.Anything, imageID, false).Return(types.ImageInspect{Os: "linux", Architecture: "amd64"}, []byte{}, nil).Once()
+	config.Image = imageID
+	constraints, err = c.resolvePlatform(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, constraints, []string{"platform.os==linux", "platform.architecture==amd64"})
+}
+
+func TestWithConstraints(t *testing.T) {
+	config := createPreemptionConfig(1)
+	assert.True(t, withConstraints(config, nil) == config)
+
+	scheduled := withConstraints(config, []string{"platform.os==linux"})
+	assert.Equal(t, scheduled.Constraints(), []string{"platform.os==linux"})
+	assert.Empty(t, config.Constraints())
+}
+
+func TestNodePlatformConstraints(t *testing.T) {
+	labels := map[string]string{cluster.OSLabel: "linux", cluster.ArchitectureLabel: "arm64"}
+	config := createPreemptionConfig(1)
+	assert.Equal(t, nodePlatformConstraints(config, labels), []string{"platform.os==linux", "platform.architecture==arm64"})
+	assert.Empty(t, nodePlatformConstraints(config, nil))
+
+	config.AddConstraint("platform.os==windows")
+	assert.Empty(t, nodePlatformConstraints(config, labels))
+}
@@ -0,0 +1,161 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// portsLabel is the engine label setting the range of host ports allocated
+// by Swarm on a node (ex. swarm.ports=30000-31000). It overrides the
+// swarm.ports cluster option.
+const portsLabel = "swarm.ports"
+
+var errNoHostPortAvailable = errors.New("unable to find a node with enough free host ports")
+
+// selectNode returns the first node where the host ports of the container can
+// be allocated, along with the allocated port bindings. The bindings are nil
+// when Swarm leaves the allocation of host ports to the engine.
+func (c *Cluster) selectNode(nodes []*node.Node, config *cluster.ContainerConfig) (*node.Node, nat.PortMap, error) {
+	if len(config.HostConfig.PortBindings) == 0 || config.HostConfig.NetworkMode.IsHost() {
+		return nodes[0], nil, nil
+	}
+
+	for _, n := range nodes {
+		first, last, ok := c.hostPortRange(n)
+		if !ok {
+			return n, nil, nil
+		}
+		bindings, err := allocatePorts(config.HostConfig.PortBindings, usedPorts(n), first, last)
+		if err != nil {
+			log.WithFields(log.Fields{"NodeName": n.Name, "NodeID": n.ID}).WithError(err).Debug("Unable to allocate host ports")
+			continue
+		}
+		return n, bindings, nil
+	}
+	return nil, nil, errNoHostPortAvailable
+}
+
+// hostPortRange returns the range of host ports Swarm allocates on a node, or
+// false if the engine allocates them.
+func (c *Cluster) hostPortRange(n *node.Node) (int, int, bool) {
+	spec := c.portRange
+	if label, ok := n.Labels[portsLabel]; ok {
+		spec = label
+	}
+	if spec == "" {
+		return 0, 0, false
+	}
+
+	first, last, err := nat.ParsePortRangeToInt(spec)
+	if err != nil || first == 0 {
+		log.WithFields(log.Fields{"NodeName": n.Name, "NodeID": n.ID}).Warnf("Invalid host port range %q, host ports are allocated by the engine", spec)
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// allocatePorts returns a copy of the port bindings where unspecified host
+// ports (-p 80) are replaced by free ports of [first, last], and host port
+// ranges (-p 8000-8010:80) by free ports of the range. Either every port is
+// allocated, or an error is returned. used is updated with the allocated
+// ports.
+func allocatePorts(bindings nat.PortMap, used map[nat.Port]struct{}, first, last int) (nat.PortMap, error) {
+	ports := make([]string, 0, len(bindings))
+	for port := range bindings {
+		ports = append(ports, string(port))
+	}
+	sort.Strings(ports)
+
+	// Reserve the explicit host ports first, so that they are not given
+	// to another binding.
+	for _, port := range ports {
+		proto := nat.Port(port).Proto()
+		for _, b := range bindings[nat.Port(port)] {
+			if hostPort, err := nat.ParsePort(b.HostPort); err == nil && hostPort != 0 {
+				used[hostPortKey(proto, hostPort)] = struct{}{}
+			}
+		}
+	}
+
+	allocated := make(nat.PortMap, len(bindings))
+	for _, port := range ports {
+		proto := nat.Port(port).Proto()
+		for _, b := range bindings[nat.Port(port)] {
+			lo, hi := first, last
+			if b.HostPort != "" {
+				var err error
+				if lo, hi, err = nat.ParsePortRangeToInt(b.HostPort); err != nil {
+					return nil, err
+				}
+				if lo == hi {
+					allocated[nat.Port(port)] = append(allocated[nat.Port(port)], b)
+					continue
+				}
+			}
+
+			hostPort, ok := freePort(used, proto, lo, hi)
+			if !ok {
+				return nil, fmt.Errorf("no free host port in %d-%d for %s", lo, hi, port)
+			}
+			used[hostPortKey(proto, hostPort)] = struct{}{}
+			allocated[nat.Port(port)] = append(allocated[nat.Port(port)], nat.PortBinding{
+				HostIP:   b.HostIP,
+				HostPort: strconv.Itoa(hostPort),
+			})
+		}
+	}
+	return allocated, nil
+}
+
+func freePort(used map[nat.Port]struct{}, proto string, first, last int) (int, bool) {
+	for port := first; port <= last; port++ {
+		if _, ok := used[hostPortKey(proto, port)]; !ok {
+			return port, true
+		}
+	}
+	return 0, false
+}
+
+// usedPorts returns the host ports bound by the containers of the node,
+// including pending containers.
+func usedPorts(n *node.Node) map[nat.Port]struct{} {
+	used := make(map[nat.Port]struct{})
+	add := func(bindings nat.PortMap) {
+		for port, list := range bindings {
+			for _, b := range list {
+				if hostPort, err := nat.ParsePort(b.HostPort); err == nil && hostPort != 0 {
+					used[hostPortKey(port.Proto(), hostPort)] = struct{}{}
+				}
+			}
+		}
+	}
+
+	for _, c := range n.Containers {
+		if c.Config != nil {
+			add(c.Config.HostConfig.PortBindings)
+		}
+		if c.Info.HostConfig != nil {
+			add(c.Info.HostConfig.PortBindings)
+			if c.Info.HostConfig.NetworkMode.IsHost() && c.Info.Config != nil {
+				for port := range c.Info.Config.ExposedPorts {
+					used[hostPortKey(port.Proto(), port.Int())] = struct{}{}
+				}
+			}
+		}
+		if c.Info.NetworkSettings != nil {
+			add(c.Info.NetworkSettings.Ports)
+		}
+	}
+	return used
+}
+
+func hostPortKey(proto string, port int) nat.Port {
+	return nat.Port(fmt.Sprintf("%d/%s", port, proto))
+}
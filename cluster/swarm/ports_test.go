@@ -0,0 +1,98 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func createPortsConfig(t *testing.T, specs ...string) *cluster.ContainerConfig {
+	exposed, bindings, err := nat.ParsePortSpecs(specs)
+	assert.NoError(t, err)
+	return cluster.BuildContainerConfig(containertypes.Config{ExposedPorts: exposed}, containertypes.HostConfig{PortBindings: bindings}, networktypes.NetworkingConfig{})
+}
+
+func createPortsNode(ID string, labels map[string]string, configs ...*cluster.ContainerConfig) *node.Node {
+	n := &node.Node{ID: ID, Name: ID, Labels: labels}
+	for _, config := range configs {
+		n.AddContainer(&cluster.Container{
+			Container: types.Container{ID: ID + "-container"},
+			Config:    config,
+			Info: types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &config.HostConfig},
+			},
+		})
+	}
+	return n
+}
+
+func TestAllocatePorts(t *testing.T) {
+	used := map[nat.Port]struct{}{
+		hostPortKey("tcp", 30000): {},
+		hostPortKey("tcp", 8000):  {},
+	}
+
+	// -p 80 -p 53/udp -p 8000-8010:443 -p 9000:9000
+	config := createPortsConfig(t, "80", "53/udp", "8000-8010:443", "9000:9000")
+	bindings, err := allocatePorts(config.HostConfig.PortBindings, used, 30000, 30010)
+	assert.NoError(t, err)
+	assert.Equal(t, bindings, nat.PortMap{
+		"443/tcp":  {{HostPort: "8001"}},
+		"53/udp":   {{HostPort: "30000"}},
+		"80/tcp":   {{HostPort: "30001"}},
+		"9000/tcp": {{HostPort: "9000"}},
+	})
+
+	// The original bindings are left untouched.
+	assert.Equal(t, config.HostConfig.PortBindings["80/tcp"], []nat.PortBinding{{}})
+
+	// Either every port is allocated, or none is.
+	used = map[nat.Port]struct{}{}
+	config = createPortsConfig(t, "80", "81", "82")
+	_, err = allocatePorts(config.HostConfig.PortBindings, used, 30000, 30001)
+	assert.Error(t, err)
+}
+
+func TestSelectNode(t *testing.T) {
+	c := &Cluster{portRange: "30000-30001"}
+
+	full := createPortsConfig(t, "30000:80", "30001:81")
+	nodes := []*node.Node{
+		createPortsNode("node-0", map[string]string{}, full),
+		createPortsNode("node-1", map[string]string{}),
+	}
+
+	// Nodes without free ports are skipped.
+	config := createPortsConfig(t, "80", "81")
+	n, bindings, err := c.selectNode(nodes, config)
+	assert.NoError(t, err)
+	assert.Equal(t, n.ID, "node-1")
+	assert.Equal(t, bindings, nat.PortMap{
+		"80/tcp": {{HostPort: "30000"}},
+		"81/tcp": {{HostPort: "30001"}},
+	})
+
+	config = createPortsConfig(t, "80", "81", "82")
+	_, _, err = c.selectNode(nodes, config)
+	assert.Equal(t, err, errNoHostPortAvailable)
+
+	// The node label overrides the range.
+	nodes[1].Labels[portsLabel] = "40000-40010"
+	n, bindings, err = c.selectNode(nodes, config)
+	assert.NoError(t, err)
+	assert.Equal(t, n.ID, "node-1")
+	assert.Equal(t, bindings["82/tcp"], []nat.PortBinding{{HostPort: "40002"}})
+
+	// Without range, the engine allocates the ports.
+	c.portRange = ""
+	n, bindings, err = c.selectNode(nodes[:1], config)
+	assert.NoError(t, err)
+	assert.Equal(t, n.ID, "node-0")
+	assert.Nil(t, bindings)
+}
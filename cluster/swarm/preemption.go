@@ -0,0 +1,143 @@
+package swarm
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types/events"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// planPreemption looks for the node where evicting the fewest containers of
+// a lower priority makes room for the container. The victims are removed from
+// the returned node, which is nil if no eviction makes room.
+func (c *Cluster) planPreemption(nodes []*node.Node, config *cluster.ContainerConfig) (*node.Node, []*cluster.Container) {
+	// Walk the nodes in a stable order, so that plans are reproducible.
+	sort.Sort(nodesByID(nodes))
+
+	var (
+		target  *node.Node
+		victims []*cluster.Container
+	)
+	for _, n := range nodes {
+		var (
+			evicted = []*cluster.Container{}
+			fits    = false
+		)
+		for _, candidate := range preemptible(n, config.Priority()) {
+			if target != nil && len(evicted)+1 >= len(victims) {
+				break
+			}
+			n.RemoveContainer(candidate)
+			evicted = append(evicted, candidate)
+			if _, err := c.scheduler.SelectNodesForContainer([]*node.Node{n}, config); err == nil {
+				fits = true
+				break
+			}
+		}
+
+		if !fits {
+			restoreContainers(n, evicted)
+			continue
+		}
+		if target != nil {
+			restoreContainers(target, victims)
+		}
+		target, victims = n, evicted
+	}
+	return target, victims
+}
+
+// preemptible returns the containers of the node with a lower priority, by
+// increasing priority. Global containers are never preempted.
+func preemptible(n *node.Node, priority int64) []*cluster.Container {
+	containers := []*cluster.Container{}
+	for _, c := range n.Containers {
+		if c.ID != "" && c.Config != nil && !c.Config.IsGlobal() && c.Config.Priority() < priority {
+			containers = append(containers, c)
+		}
+	}
+	sort.Sort(containersByPriority(containers))
+	return containers
+}
+
+func restoreContainers(n *node.Node, containers []*cluster.Container) {
+	for _, c := range containers {
+		n.AddContainer(c)
+	}
+}
+
+// evictContainers stops and removes the preempted containers, keeping their
+// volumes, and returns the ones which should be rescheduled.
+func (c *Cluster) evictContainers(victims []*cluster.Container, by string) []*cluster.Container {
+	reschedule := []*cluster.Container{}
+	for _, victim := range victims {
+		if err := victim.Engine.RemoveContainer(victim, true, false); err != nil {
+			log.WithFields(log.Fields{"NodeName": victim.Engine.Name, "NodeID": victim.Engine.ID}).WithError(err).Errorf("Failed to preempt container %s", victim.ID)
+			continue
+		}
+		log.Infof("Preempted container %s on %s for %s", victim.ID, victim.Engine.Name, by)
+		c.emitPreemptionEvent(victim, by)
+
+		if victim.Config.HasReschedulePolicy("on-node-failure") {
+			reschedule = append(reschedule, victim)
+		}
+	}
+	return reschedule
+}
+
+// reschedulePreempted creates the preempted containers again, on other nodes.
+func (c *Cluster) reschedulePreempted(containers []*cluster.Container) {
+	for _, container := range containers {
+		newContainer, err := c.CreateContainer(container.Config, strings.TrimPrefix(container.Info.Name, "/"), nil)
+		if err != nil {
+			log.Errorf("Failed to reschedule preempted container %s: %v", container.ID, err)
+			continue
+		}
+
+		log.Infof("Rescheduled preempted container %s from %s to %s as %s", container.ID, container.Engine.Name, newContainer.Engine.Name, newContainer.ID)
+		if container.Info.State != nil && container.Info.State.Running {
+			if err := c.StartContainer(newContainer, nil); err != nil {
+				log.Errorf("Failed to start rescheduled container %s: %v", newContainer.ID, err)
+			}
+		}
+	}
+}
+
+func (c *Cluster) emitPreemptionEvent(victim *cluster.Container, by string) {
+	now := time.Now()
+	c.Handle(&cluster.Event{
+		Message: events.Message{
+			Status: "preempted",
+			ID:     victim.ID,
+			From:   "swarm",
+			Type:   "swarm",
+			Action: "preempted",
+			Actor: events.Actor{
+				ID: victim.ID,
+				Attributes: map[string]string{
+					"name":         strings.TrimPrefix(victim.Info.Name, "/"),
+					"node":         victim.Engine.Name,
+					"preempted.by": by,
+				},
+			},
+			Time:     now.Unix(),
+			TimeNano: now.UnixNano(),
+		},
+		Engine: victim.Engine,
+	})
+}
+
+type containersByPriority []*cluster.Container
+
+func (c containersByPriority) Len() int      { return len(c) }
+func (c containersByPriority) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c containersByPriority) Less(i, j int) bool {
+	if c[i].Config.Priority() != c[j].Config.Priority() {
+		return c[i].Config.Priority() < c[j].Config.Priority()
+	}
+	return c[i].ID < c[j].ID
+}
@@ -0,0 +1,98 @@
+package swarm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func createPreemptionConfig(memory int64, env ...string) *cluster.ContainerConfig {
+	return cluster.BuildContainerConfig(containertypes.Config{Env: env}, containertypes.HostConfig{
+		Resources: containertypes.Resources{Memory: memory},
+	}, networktypes.NetworkingConfig{})
+}
+
+func createPreemptionNode(ID string, memory int64, configs ...*cluster.ContainerConfig) *node.Node {
+	n := &node.Node{
+		ID:              ID,
+		Name:            ID,
+		Labels:          map[string]string{},
+		TotalMemory:     memory,
+		TotalCpus:       1,
+		HealthIndicator: 100,
+	}
+	for i, config := range configs {
+		n.AddContainer(&cluster.Container{
+			Container: types.Container{ID: fmt.Sprintf("%s-%d", ID, i)},
+			Config:    config,
+		})
+	}
+	return n
+}
+
+func TestPlanPreemption(t *testing.T) {
+	c := createRebalanceCluster(t, "spread")
+
+	createNodes := func() []*node.Node {
+		return []*node.Node{
+			createPreemptionNode("node-0", 4,
+				createPreemptionConfig(1, "priority:1"),
+				createPreemptionConfig(3, "priority:1")),
+			// The lowest priority goes first.
+			createPreemptionNode("node-1", 4,
+				createPreemptionConfig(2, "priority:5"),
+				createPreemptionConfig(2, "priority:-1")),
+			// Nothing can be evicted.
+			createPreemptionNode("node-2", 4,
+				createPreemptionConfig(4, "priority:10")),
+		}
+	}
+
+	nodes := createNodes()
+	config := createPreemptionConfig(3, "priority:10")
+	_, err := c.scheduler.SelectNodesForContainer(nodes, config)
+	assert.Error(t, err)
+
+	target, victims := c.planPreemption(nodes, config)
+	assert.Equal(t, target.ID, "node-0")
+	assert.Len(t, victims, 2)
+	assert.Empty(t, target.Containers)
+
+	// The node evicting the fewest containers wins.
+	nodes = createNodes()
+	config = createPreemptionConfig(2, "priority:10")
+	target, victims = c.planPreemption(nodes, config)
+	assert.Equal(t, target.ID, "node-1")
+	assert.Len(t, victims, 1)
+	assert.Equal(t, victims[0].ID, "node-1-1")
+	assert.Len(t, target.Containers, 1)
+
+	// The other nodes are left untouched.
+	assert.Len(t, nodes[0].Containers, 2)
+	assert.Equal(t, nodes[0].UsedMemory, int64(4))
+	assert.Len(t, nodes[2].Containers, 1)
+
+	// Containers of the same priority are never evicted.
+	nodes = []*node.Node{
+		createPreemptionNode("node-0", 4,
+			createPreemptionConfig(4, "priority:1")),
+	}
+	target, victims = c.planPreemption(nodes, createPreemptionConfig(2, "priority:1"))
+	assert.Nil(t, target)
+	assert.Empty(t, victims)
+	assert.Len(t, nodes[0].Containers, 1)
+
+	// Neither are global containers.
+	nodes = []*node.Node{
+		createPreemptionNode("node-0", 4,
+			createPreemptionConfig(4, "mode:global")),
+	}
+	target, _ = c.planPreemption(nodes, createPreemptionConfig(2, "priority:1"))
+	assert.Nil(t, target)
+}
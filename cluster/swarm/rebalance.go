@@ -0,0 +1,270 @@
+package swarm
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types/events"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// defaultRebalanceDelay is the default time to wait between two migrations.
+const defaultRebalanceDelay = 10 * time.Second
+
+var (
+	errRebalanceInProgress = errors.New("Rebalancing is already in progress")
+	errRebalanceRandom     = errors.New("Rebalancing is not supported with the random strategy")
+)
+
+// Rebalance moves the containers with an "on-node-failure" reschedule policy
+// to the nodes preferred by the placement strategy. Migrations are carried out
+// in the background, one at a time, waiting for the rebalance delay between
+// each of them.
+func (c *Cluster) Rebalance(dryRun bool) ([]*cluster.Migration, error) {
+	if c.scheduler.Strategy() == "random" {
+		return nil, errRebalanceRandom
+	}
+
+	if !dryRun {
+		c.rebalanceLock.Lock()
+		if c.rebalancing {
+			c.rebalanceLock.Unlock()
+			return nil, errRebalanceInProgress
+		}
+		c.rebalancing = true
+		c.rebalanceLock.Unlock()
+	}
+
+	c.scheduler.Lock()
+	migrations := c.planMigrations(c.listNodes())
+	c.scheduler.Unlock()
+
+	if !dryRun {
+		go c.migrateContainers(migrations)
+	}
+	return migrations, nil
+}
+
+// triggerRebalance runs the rebalancer once the cluster has been stable for
+// the rebalance delay. Triggers occurring in the meantime are coalesced.
+func (c *Cluster) triggerRebalance() {
+	if !c.rebalance {
+		return
+	}
+
+	c.rebalanceLock.Lock()
+	defer c.rebalanceLock.Unlock()
+
+	if c.rebalanceTimer != nil {
+		c.rebalanceTimer.Reset(c.rebalanceDelay)
+		return
+	}
+	c.rebalanceTimer = time.AfterFunc(c.rebalanceDelay, func() {
+		c.rebalanceLock.Lock()
+		c.rebalanceTimer = nil
+		c.rebalanceLock.Unlock()
+
+		if _, err := c.Rebalance(false); err != nil {
+			log.Warnf("Unable to rebalance the cluster: %v", err)
+		}
+	})
+}
+
+// planMigrations simulates the rescheduling of every movable container on the
+// nodes, and returns the containers the strategy would rather put elsewhere.
+// The nodes are updated as migrations are planned.
+func (c *Cluster) planMigrations(nodes []*node.Node) []*cluster.Migration {
+	// Walk the nodes and containers in a stable order, so that plans are
+	// reproducible.
+	sort.Sort(nodesByID(nodes))
+
+	migrations := []*cluster.Migration{}
+	planned := make(map[string]struct{})
+	for _, n := range nodes {
+		if !n.IsHealthy() {
+			continue
+		}
+
+		for _, container := range n.Containers {
+			if _, ok := planned[container.ID]; ok || !isMovable(container) {
+				continue
+			}
+
+			target := c.preferredNode(nodes, n, container)
+			if target == nil {
+				continue
+			}
+
+			n.RemoveContainer(container)
+			target.AddContainer(container)
+			planned[container.ID] = struct{}{}
+			migrations = append(migrations, &cluster.Migration{
+				ID:   container.ID,
+				Name: strings.TrimPrefix(container.Info.Name, "/"),
+				From: n.Name,
+				To:   target.Name,
+			})
+		}
+	}
+	return migrations
+}
+
+// preferredNode returns the node the container should be moved to, or nil if
+// it should stay on its current node.
+func (c *Cluster) preferredNode(nodes []*node.Node, current *node.Node, container *cluster.Container) *node.Node {
+	// Rank the nodes as if the container was being scheduled for the first
+	// time.
+	current.RemoveContainer(container)
+	defer current.AddContainer(container)
+
+	// Keep the container on the platform it runs on.
+	config := withConstraints(container.Config, nodePlatformConstraints(container.Config, current.Labels))
+	candidates, err := c.scheduler.SelectNodesForContainer(nodes, config)
+	if err != nil || len(candidates) == 0 || candidates[0].ID == current.ID {
+		return nil
+	}
+	target := candidates[0]
+
+	// Only move the container if the strategy strictly prefers the target,
+	// otherwise containers would bounce between equivalent nodes.
+	for _, pair := range [][]*node.Node{{current, target}, {target, current}} {
+		ranked, err := c.scheduler.SelectNodesForContainer(pair, config)
+		if err != nil || len(ranked) == 0 || ranked[0].ID != target.ID {
+			return nil
+		}
+	}
+	return target
+}
+
+// migrateContainers carries out the planned migrations.
+func (c *Cluster) migrateContainers(migrations []*cluster.Migration) {
+	defer func() {
+		c.rebalanceLock.Lock()
+		c.rebalancing = false
+		c.rebalanceLock.Unlock()
+	}()
+
+	for i, m := range migrations {
+		if i > 0 {
+			time.Sleep(c.rebalanceDelay)
+		}
+		if err := c.migrateContainer(m); err != nil {
+			log.WithFields(log.Fields{"From": m.From, "To": m.To}).WithError(err).Errorf("Failed to migrate container %s", m.ID)
+		}
+	}
+}
+
+// migrateContainer moves a container to another engine. The copy is created
+// and started before the original container is removed, keeping its volumes.
+// The target is checked against the filters again, since the cluster may have
+// changed since the migration was planned.
+func (c *Cluster) migrateContainer(m *cluster.Migration) error {
+	container := c.Container(m.ID)
+	if container == nil || container.Engine.Name != m.From {
+		return errors.New("container has been removed or moved in the meantime")
+	}
+	target := c.getEngineByIDOrName(m.To)
+	if target == nil || !target.IsSchedulable() {
+		return errors.New("target node is not available anymore")
+	}
+
+	// Reserve the resources on the target while the container is created.
+	config := container.Config.Copy()
+	c.scheduler.Lock()
+	if !c.acceptsContainer(target.ID, withConstraints(config, nodePlatformConstraints(config, container.Engine.Labels))) {
+		c.scheduler.Unlock()
+		return errors.New("target node doesn't accept the container anymore")
+	}
+	c.reserve(config.SwarmID(), &pendingContainer{
+		Config: config,
+		Engine: target,
+	})
+	c.scheduler.Unlock()
+
+	newContainer, err := target.CreateContainer(config, "", true, nil)
+
+	c.scheduler.Lock()
+	delete(c.pendingContainers, config.SwarmID())
+	c.scheduler.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if container.Info.State != nil && container.Info.State.Running {
+		if err := target.StartContainer(newContainer.ID, nil); err != nil {
+			target.RemoveContainer(newContainer, true, false)
+			return err
+		}
+	}
+
+	if err := container.Engine.RemoveContainer(container, true, false); err != nil {
+		target.RemoveContainer(newContainer, true, false)
+		return err
+	}
+
+	// The copy took a generated name, give it back the original one.
+	if m.Name != "" {
+		if err := target.RenameContainer(newContainer, m.Name); err != nil {
+			log.WithFields(log.Fields{"NodeName": target.Name, "NodeID": target.ID}).WithError(err).Errorf("Failed to rename migrated container %s to %s", newContainer.ID, m.Name)
+		}
+	}
+
+	log.Infof("Migrated container %s from %s to %s as %s", m.ID, m.From, m.To, newContainer.ID)
+	c.emitMigrationEvent(m, newContainer)
+	return nil
+}
+
+// acceptsContainer returns true if the filters accept the container on the
+// node. Must be called with the scheduler lock held.
+func (c *Cluster) acceptsContainer(nodeID string, config *cluster.ContainerConfig) bool {
+	for _, n := range c.listNodes() {
+		if n.ID == nodeID {
+			candidates, err := c.scheduler.SelectNodesForContainer([]*node.Node{n}, config)
+			return err == nil && len(candidates) == 1
+		}
+	}
+	return false
+}
+
+func (c *Cluster) emitMigrationEvent(m *cluster.Migration, newContainer *cluster.Container) {
+	now := time.Now()
+	c.Handle(&cluster.Event{
+		Message: events.Message{
+			Status: "container_migrate",
+			ID:     newContainer.ID,
+			From:   "swarm",
+			Type:   "swarm",
+			Action: "container_migrate",
+			Actor: events.Actor{
+				ID: newContainer.ID,
+				Attributes: map[string]string{
+					"name":      m.Name,
+					"old.id":    m.ID,
+					"from.node": m.From,
+					"to.node":   m.To,
+				},
+			},
+			Time:     now.Unix(),
+			TimeNano: now.UnixNano(),
+		},
+		Engine: newContainer.Engine,
+	})
+}
+
+// isMovable returns true if the rebalancer is allowed to move the container.
+func isMovable(container *cluster.Container) bool {
+	return container.ID != "" && container.Config != nil &&
+		container.Config.HasReschedulePolicy("on-node-failure") &&
+		!container.Config.IsGlobal()
+}
+
+type nodesByID []*node.Node
+
+func (n nodesByID) Len() int           { return len(n) }
+func (n nodesByID) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n nodesByID) Less(i, j int) bool { return n[i].ID < n[j].ID }
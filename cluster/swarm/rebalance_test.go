@@ -0,0 +1,149 @@
+package swarm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+func createRebalanceNode(ID string, containers int, env ...string) *node.Node {
+	n := &node.Node{
+		ID:              ID,
+		Name:            ID,
+		Labels:          map[string]string{},
+		HealthIndicator: 100,
+	}
+	for i := 0; i < containers; i++ {
+		config := cluster.BuildContainerConfig(containertypes.Config{Env: env}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+		n.AddContainer(&cluster.Container{
+			Container: types.Container{ID: fmt.Sprintf("%s-%d", ID, i)},
+			Config:    config,
+			Info: types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{Name: fmt.Sprintf("/%s-%d", ID, i)},
+			},
+		})
+	}
+	return n
+}
+
+func createRebalanceCluster(t *testing.T, name string) *Cluster {
+	s, err := strategy.New(name)
+	assert.NoError(t, err)
+	filters, err := filter.New([]string{"health", "constraint"})
+	assert.NoError(t, err)
+
+	return &Cluster{
+		engines:           make(map[string]*cluster.Engine),
+		pendingContainers: make(map[string]*pendingContainer),
+		scheduler:         scheduler.New(s, filters),
+	}
+}
+
+func TestPlanMigrationsSpread(t *testing.T) {
+	c := createRebalanceCluster(t, "spread")
+
+	nodes := []*node.Node{
+		createRebalanceNode("node-1", 5, "reschedule:on-node-failure"),
+		createRebalanceNode("node-2", 0),
+	}
+	migrations := c.planMigrations(nodes)
+	assert.Len(t, migrations, 2)
+	for _, m := range migrations {
+		assert.Equal(t, m.From, "node-1")
+		assert.Equal(t, m.To, "node-2")
+		assert.Equal(t, m.Name, m.ID)
+	}
+	assert.Len(t, nodes[0].Containers, 3)
+	assert.Len(t, nodes[1].Containers, 2)
+
+	// The cluster is balanced, nothing should move anymore.
+	assert.Empty(t, c.planMigrations(nodes))
+}
+
+func TestPlanMigrationsPolicy(t *testing.T) {
+	c := createRebalanceCluster(t, "spread")
+
+	// Containers without a reschedule policy never move.
+	nodes := []*node.Node{
+		createRebalanceNode("node-1", 5),
+		createRebalanceNode("node-2", 0),
+	}
+	assert.Empty(t, c.planMigrations(nodes))
+
+	// Neither do global containers.
+	nodes = []*node.Node{
+		createRebalanceNode("node-1", 5, "reschedule:on-node-failure", "mode:global"),
+		createRebalanceNode("node-2", 0),
+	}
+	assert.Empty(t, c.planMigrations(nodes))
+}
+
+func TestPlanMigrationsFilters(t *testing.T) {
+	c := createRebalanceCluster(t, "spread")
+
+	// Unhealthy nodes are not used as a target.
+	nodes := []*node.Node{
+		createRebalanceNode("node-1", 5, "reschedule:on-node-failure"),
+		createRebalanceNode("node-2", 0),
+	}
+	nodes[1].HealthIndicator = 0
+	assert.Empty(t, c.planMigrations(nodes))
+
+	// Constraints are honoured.
+	nodes = []*node.Node{
+		createRebalanceNode("node-1", 5, "reschedule:on-node-failure", "constraint:node==node-1"),
+		createRebalanceNode("node-2", 0),
+	}
+	assert.Empty(t, c.planMigrations(nodes))
+}
+
+func TestPlanMigrationsBinpack(t *testing.T) {
+	c := createRebalanceCluster(t, "binpack")
+
+	nodes := []*node.Node{
+		createRebalanceNode("node-1", 1, "reschedule:on-node-failure"),
+		createRebalanceNode("node-2", 3, "reschedule:on-node-failure"),
+	}
+	migrations := c.planMigrations(nodes)
+	assert.Len(t, migrations, 1)
+	assert.Equal(t, migrations[0].From, "node-1")
+	assert.Equal(t, migrations[0].To, "node-2")
+	assert.Empty(t, c.planMigrations(nodes))
+}
+
+func TestRebalanceRandom(t *testing.T) {
+	c := createRebalanceCluster(t, "random")
+
+	_, err := c.Rebalance(true)
+	assert.Error(t, err)
+}
+
+func TestAcceptsContainer(t *testing.T) {
+	s, err := strategy.New("spread")
+	assert.NoError(t, err)
+	filters, err := filter.New([]string{"constraint"})
+	assert.NoError(t, err)
+	c := &Cluster{
+		engines:           make(map[string]*cluster.Engine),
+		pendingContainers: make(map[string]*pendingContainer),
+		nodes:             newNodeStore(),
+		scheduler:         scheduler.New(s, filters),
+	}
+	c.engines["node-1"] = createEngine(t, "node-1")
+	c.engines["node-2"] = createEngine(t, "node-2")
+
+	// The target is checked against the filters before each migration.
+	config := cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:node==node-1"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	assert.True(t, c.acceptsContainer("node-1", config))
+	assert.False(t, c.acceptsContainer("node-2", config))
+	assert.False(t, c.acceptsContainer("node-3", config))
+}
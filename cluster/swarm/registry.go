@@ -0,0 +1,302 @@
+package swarm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+)
+
+const (
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeManifestV1   = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+
+	// defaultRegistryTimeout bounds the requests to the registries, unless
+	// set with swarm.registry.timeout.
+	defaultRegistryTimeout = 3 * time.Second
+	// registryCacheTTL is how long the result of a lookup is remembered, so
+	// that creating many containers doesn't query the registry every time.
+	registryCacheTTL = time.Minute
+	// maxManifestSize bounds the manifests and image configs read.
+	maxManifestSize = 4 << 20
+)
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryClient finds the platforms of an image in its registry, from its
+// manifest list or from the config of its manifest.
+type registryClient struct {
+	client *http.Client
+	// insecure are the registries contacted over plain HTTP, by host:port.
+	insecure map[string]bool
+
+	mu    sync.Mutex
+	cache map[string]registryLookup
+}
+
+type registryLookup struct {
+	platforms []cluster.Platform
+	err       error
+	expires   time.Time
+}
+
+func newRegistryClient(insecure []string, timeout time.Duration) *registryClient {
+	r := &registryClient{
+		client:   &http.Client{Timeout: timeout},
+		insecure: make(map[string]bool),
+		cache:    make(map[string]registryLookup),
+	}
+	for _, host := range insecure {
+		if host = strings.TrimSpace(host); host != "" {
+			r.insecure[host] = true
+		}
+	}
+	return r
+}
+
+// Platforms returns the platforms of the image. A nil client finds nothing.
+func (r *registryClient) Platforms(image string, authConfig *types.AuthConfig) ([]cluster.Platform, error) {
+	if r == nil {
+		return nil, errors.New("no registry client")
+	}
+
+	key := image
+	if authConfig != nil {
+		key = authConfig.Username + "@" + image
+	}
+	now := time.Now()
+	r.mu.Lock()
+	lookup, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && now.Before(lookup.expires) {
+		return lookup.platforms, lookup.err
+	}
+
+	platforms, err := r.lookup(image, authConfig)
+
+	r.mu.Lock()
+	for k, l := range r.cache {
+		if !now.Before(l.expires) {
+			delete(r.cache, k)
+		}
+	}
+	r.cache[key] = registryLookup{platforms: platforms, err: err, expires: now.Add(registryCacheTTL)}
+	r.mu.Unlock()
+	return platforms, err
+}
+
+func (r *registryClient) lookup(image string, authConfig *types.AuthConfig) ([]cluster.Platform, error) {
+	host, repository, ref, err := parseImageReference(image)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "https"
+	if r.insecure[host] {
+		scheme = "http"
+	}
+	base := fmt.Sprintf("%s://%s/v2/%s", scheme, host, repository)
+
+	var authorization string
+	body, mediaType, err := r.get(base+"/manifests/"+ref, []string{mediaTypeManifestList, mediaTypeManifest, mediaTypeManifestV1}, authConfig, &authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mediaType {
+	case mediaTypeManifestList:
+		var list struct {
+			Manifests []struct {
+				Platform struct {
+					Architecture string `json:"architecture"`
+					OS           string `json:"os"`
+				} `json:"platform"`
+			} `json:"manifests"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+		platforms := []cluster.Platform{}
+		for _, m := range list.Manifests {
+			if m.Platform.OS == "unknown" || m.Platform.Architecture == "unknown" {
+				continue
+			}
+			platforms = append(platforms, cluster.Platform{OS: m.Platform.OS, Architecture: cluster.NormalizeArchitecture(m.Platform.Architecture)})
+		}
+		return platforms, nil
+
+	case mediaTypeManifest:
+		var manifest struct {
+			Config struct {
+				Digest string `json:"digest"`
+			} `json:"config"`
+		}
+		if err := json.Unmarshal(body, &manifest); err != nil {
+			return nil, err
+		}
+		body, _, err = r.get(base+"/blobs/"+manifest.Config.Digest, nil, authConfig, &authorization)
+		if err != nil {
+			return nil, err
+		}
+		var config struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		}
+		if err := json.Unmarshal(body, &config); err != nil {
+			return nil, err
+		}
+		return []cluster.Platform{{OS: config.OS, Architecture: cluster.NormalizeArchitecture(config.Architecture)}}, nil
+	}
+
+	// Schema 1 manifests only tell the architecture.
+	var manifest struct {
+		Architecture string `json:"architecture"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, err
+	}
+	return []cluster.Platform{{Architecture: cluster.NormalizeArchitecture(manifest.Architecture)}}, nil
+}
+
+// get fetches a document from the registry, authorizing the request when the
+// registry asks for it. authorization caches the Authorization header for the
+// following requests to the same repository.
+func (r *registryClient) get(u string, accept []string, authConfig *types.AuthConfig, authorization *string) ([]byte, string, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, mediaType := range accept {
+			req.Header.Add("Accept", mediaType)
+		}
+		if *authorization != "" {
+			req.Header.Set("Authorization", *authorization)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			if *authorization, err = r.authorize(resp.Header.Get("WWW-Authenticate"), authConfig); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("unexpected status %s from %s", resp.Status, u)
+		}
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		return body, mediaType, nil
+	}
+}
+
+// authorize returns the Authorization header answering the challenge of a
+// registry, with a token from its authorization server for Bearer challenges.
+func (r *registryClient) authorize(challenge string, authConfig *types.AuthConfig) (string, error) {
+	parts := strings.SplitN(challenge, " ", 2)
+	params := map[string]string{}
+	if len(parts) == 2 {
+		for _, match := range challengeParamRegexp.FindAllStringSubmatch(parts[1], -1) {
+			params[strings.ToLower(match[1])] = match[2]
+		}
+	}
+
+	hasCredentials := authConfig != nil && authConfig.Username != ""
+	switch strings.ToLower(parts[0]) {
+	case "basic":
+		if !hasCredentials {
+			return "", errors.New("the registry requires credentials")
+		}
+		req, _ := http.NewRequest("GET", "", nil)
+		req.SetBasicAuth(authConfig.Username, authConfig.Password)
+		return req.Header.Get("Authorization"), nil
+
+	case "bearer":
+		if authConfig != nil && authConfig.RegistryToken != "" {
+			return "Bearer " + authConfig.RegistryToken, nil
+		}
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("invalid registry challenge %q", challenge)
+		}
+		query := realm.Query()
+		for _, key := range []string{"service", "scope"} {
+			if params[key] != "" {
+				query.Set(key, params[key])
+			}
+		}
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequest("GET", realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if hasCredentials {
+			req.SetBasicAuth(authConfig.Username, authConfig.Password)
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status %s from %s", resp.Status, realm.Host)
+		}
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&token); err != nil {
+			return "", err
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	}
+	return "", fmt.Errorf("unsupported registry challenge %q", challenge)
+}
+
+// parseImageReference returns the registry host, the repository and the tag
+// or digest of an image.
+func parseImageReference(image string) (host, repository, ref string, err error) {
+	named, err := reference.ParseNamed(image)
+	if err != nil {
+		return "", "", "", err
+	}
+	host, repository = reference.SplitHostname(named)
+	if host == "" || host == "docker.io" || host == "index.docker.io" {
+		host = "registry-1.docker.io"
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+
+	ref = "latest"
+	if digested, ok := named.(reference.Digested); ok {
+		ref = digested.Digest().String()
+	} else if tagged, ok := named.(reference.Tagged); ok {
+		ref = tagged.Tag()
+	}
+	return host, repository, ref, nil
+}
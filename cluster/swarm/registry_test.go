@@ -0,0 +1,92 @@
+package swarm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+// createRegistry starts a registry serving a manifest list for multi:latest
+// and a manifest for single:1.0, behind a token server.
+func createRegistry(t *testing.T) (*httptest.Server, *registryClient) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Query().Get("service"), "registry")
+		fmt.Fprint(w, `{"token": "secret"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:foo:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/multi/manifests/latest":
+			w.Header().Set("Content-Type", mediaTypeManifestList)
+			fmt.Fprint(w, `{"manifests": [
+				{"platform": {"architecture": "amd64", "os": "linux"}},
+				{"platform": {"architecture": "arm64", "os": "linux"}},
+				{"platform": {"architecture": "unknown", "os": "unknown"}}
+			]}`)
+		case "/v2/single/manifests/1.0":
+			w.Header().Set("Content-Type", mediaTypeManifest)
+			fmt.Fprint(w, `{"config": {"digest": "sha256:abc"}}`)
+		case "/v2/single/blobs/sha256:abc":
+			fmt.Fprint(w, `{"architecture": "arm64", "os": "linux"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server = httptest.NewServer(mux)
+
+	r := newRegistryClient([]string{strings.TrimPrefix(server.URL, "http://")}, defaultRegistryTimeout)
+	return server, r
+}
+
+func TestRegistryPlatforms(t *testing.T) {
+	server, r := createRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	platforms, err := r.Platforms(host+"/multi", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, platforms, []cluster.Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}})
+
+	platforms, err = r.Platforms(host+"/single:1.0", &types.AuthConfig{Username: "user", Password: "password"})
+	assert.NoError(t, err)
+	assert.Equal(t, platforms, []cluster.Platform{{OS: "linux", Architecture: "arm64"}})
+
+	_, err = r.Platforms(host+"/missing", nil)
+	assert.Error(t, err)
+
+	// The lookups are cached.
+	server.Close()
+	platforms, err = r.Platforms(host+"/multi", nil)
+	assert.NoError(t, err)
+	assert.Len(t, platforms, 2)
+
+	var nilClient *registryClient
+	_, err = nilClient.Platforms(host+"/multi", nil)
+	assert.Error(t, err)
+}
+
+func TestParseImageReference(t *testing.T) {
+	host, repository, ref, err := parseImageReference("busybox")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{host, repository, ref}, []string{"registry-1.docker.io", "library/busybox", "latest"})
+
+	host, repository, ref, err = parseImageReference("localhost:5000/foo/bar:1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{host, repository, ref}, []string{"localhost:5000", "foo/bar", "1.0"})
+
+	_, _, ref, err = parseImageReference("foo/bar@sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb")
+	assert.NoError(t, err)
+	assert.Equal(t, ref, "sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb")
+}
@@ -0,0 +1,138 @@
+package swarm
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/docker/swarm/scheduler/strategy"
+)
+
+// maxOptimisticAttempts is the number of times the placement of a container
+// is decided without holding scheduler.Lock. Once exhausted, the placement is
+// decided under the lock, which can't conflict.
+const maxOptimisticAttempts = 3
+
+// reserve records a pending container in the reservation table. Until the
+// engine reports the container, it reserves the CPUs, memory, resources and
+// host ports of its config on the engine. Must be called with scheduler.Lock
+// held.
+func (c *Cluster) reserve(swarmID string, p *pendingContainer) {
+	c.pendingContainers[swarmID] = p
+	c.reservationSeq++
+	if c.reservedAt == nil {
+		c.reservedAt = make(map[string]uint64)
+	}
+	c.reservedAt[p.Engine.ID] = c.reservationSeq
+}
+
+// reservedSince returns true if a container was reserved on the engine after
+// the reservation sequence seq. Must be called with scheduler.Lock held.
+func (c *Cluster) reservedSince(engineID string, seq uint64) bool {
+	return c.reservedAt[engineID] > seq
+}
+
+// reserveContainer schedules a container and reserves its resources on the
+// selected engine. The placement is decided concurrently with other creates
+// on a snapshot of the nodes, and is retried if a container was reserved on
+// the selected engine in the meantime. It also returns the containers to
+// evict to make room for the container. The extra constraints only apply to the
+// scheduling.
+func (c *Cluster) reserveContainer(config *cluster.ContainerConfig, name string, withImageAffinity bool, constraints []string) (*pendingContainer, []*cluster.Container, error) {
+	for attempt := 1; ; attempt++ {
+		optimistic := attempt <= maxOptimisticAttempts
+
+		c.scheduler.Lock()
+		// Ensure the name is available
+		if !c.checkNameUniqueness(name) {
+			c.scheduler.Unlock()
+			return nil, nil, fmt.Errorf("Conflict: The name %s is already assigned. You have to delete (or rename) that container to be able to assign %s to a container again.", name, name)
+		}
+
+		swarmID := config.SwarmID()
+		if swarmID == "" {
+			// Associate a Swarm ID to the container we are creating.
+			swarmID = c.generateUniqueID()
+			config.SetSwarmID(swarmID)
+		}
+
+		nodes := c.listNodes()
+		seq := c.reservationSeq
+		if optimistic {
+			c.scheduler.Unlock()
+		}
+
+		p, victims, err := c.placeContainer(nodes, config, name, withImageAffinity, constraints)
+
+		if optimistic {
+			c.scheduler.Lock()
+			if err == nil && (c.reservedSince(p.Engine.ID, seq) || !c.checkNameUniqueness(name)) {
+				c.scheduler.Unlock()
+				log.WithFields(log.Fields{"NodeName": p.Engine.Name, "NodeID": p.Engine.ID}).Debugf("Conflicting reservation, retrying to schedule container %s", swarmID)
+				continue
+			}
+		}
+		if err != nil {
+			c.scheduler.Unlock()
+			return nil, nil, err
+		}
+
+		c.reserve(swarmID, p)
+		c.scheduler.Unlock()
+		return p, victims, nil
+	}
+}
+
+// placeContainer selects the engine of a container among nodes. It does not
+// need scheduler.Lock, as nodes is a view owned by the caller.
+func (c *Cluster) placeContainer(nodes []*node.Node, config *cluster.ContainerConfig, name string, withImageAffinity bool, constraints []string) (*pendingContainer, []*cluster.Container, error) {
+	// The container is scheduled with the image affinity and the extra
+	// constraints, but created without them.
+	scheduled := withConstraints(config, constraints)
+	if withImageAffinity {
+		if scheduled == config {
+			scheduled = config.Copy()
+		}
+		scheduled.AddAffinity("image==" + config.Image)
+	}
+
+	candidates, err := c.scheduler.SelectNodesForContainer(nodes, scheduled)
+
+	// Make room for the container by evicting containers of a lower
+	// priority.
+	var victims []*cluster.Container
+	if err == strategy.ErrNoResourcesAvailable && c.preemption {
+		if n, evicted := c.planPreemption(nodes, scheduled); n != nil {
+			candidates, victims, err = []*node.Node{n}, evicted, nil
+		}
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+	n, bindings, err := c.selectNode(candidates, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.RLock()
+	engine, ok := c.engines[n.ID]
+	c.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("error creating container")
+	}
+
+	// Create the container with the host ports allocated by Swarm, and
+	// keep the original bindings for the retries.
+	if bindings != nil {
+		config = config.Copy()
+		config.HostConfig.PortBindings = bindings
+	}
+
+	return &pendingContainer{
+		Name:   name,
+		Config: config,
+		Engine: engine,
+	}, victims, nil
+}
@@ -0,0 +1,61 @@
+package swarm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedSince(t *testing.T) {
+	c := createGroupCluster(t)
+
+	seq := c.reservationSeq
+	assert.False(t, c.reservedSince("node-1", seq))
+
+	c.reserve("1", &pendingContainer{Config: createGroupConfig(), Engine: c.engines["node-1"]})
+	assert.True(t, c.reservedSince("node-1", seq))
+	assert.False(t, c.reservedSince("node-2", seq))
+	assert.False(t, c.reservedSince("node-1", c.reservationSeq))
+	assert.Len(t, c.pendingContainers, 1)
+}
+
+func TestReserveContainerConcurrently(t *testing.T) {
+	s, err := strategy.New("spread")
+	assert.NoError(t, err)
+	filters, err := filter.New([]string{"constraint"})
+	assert.NoError(t, err)
+
+	c := &Cluster{
+		engines:           createSnapshotEngines(2, 0),
+		pendingContainers: make(map[string]*pendingContainer),
+		scheduler:         scheduler.New(s, filters),
+		nodes:             newNodeStore(),
+	}
+	for _, engine := range c.engines {
+		engine.Memory = 25
+	}
+
+	// The 50 containers fill both nodes without overcommitting them.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := c.reserveContainer(createPreemptionConfig(1), "", false, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, c.pendingContainers, 50)
+	for _, n := range c.listNodes() {
+		assert.Equal(t, n.UsedMemory, int64(25))
+	}
+
+	_, _, err = c.reserveContainer(createPreemptionConfig(1), "", false, nil)
+	assert.Equal(t, err, strategy.ErrNoResourcesAvailable)
+}
@@ -0,0 +1,71 @@
+package swarm
+
+import (
+	"sync"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// nodeSnapshot is the scheduler view of an engine at a given generation.
+type nodeSnapshot struct {
+	engine     *cluster.Engine
+	generation uint64
+	node       *node.Node
+}
+
+// nodeStore caches the scheduler view of every engine so that listing the
+// nodes only rebuilds the ones whose engine changed since the last call.
+type nodeStore struct {
+	sync.Mutex
+
+	snapshots map[string]*nodeSnapshot
+}
+
+func newNodeStore() *nodeStore {
+	return &nodeStore{
+		snapshots: make(map[string]*nodeSnapshot),
+	}
+}
+
+// Nodes returns a copy-on-write view of the node of every engine. Callers may
+// add or remove containers on the returned nodes without affecting the store.
+func (s *nodeStore) Nodes(engines map[string]*cluster.Engine) []*node.Node {
+	out := make([]*node.Node, 0, len(engines))
+	if s == nil {
+		for _, e := range engines {
+			out = append(out, node.NewNode(e))
+		}
+		return out
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for id, e := range engines {
+		// Read the generation before building the node: if the engine
+		// changes in between, the snapshot is rebuilt on the next call.
+		generation := e.Generation()
+		snapshot, ok := s.snapshots[id]
+		if !ok || snapshot.engine != e || snapshot.generation != generation {
+			snapshot = &nodeSnapshot{
+				engine:     e,
+				generation: generation,
+				node:       node.NewNode(e),
+			}
+			s.snapshots[id] = snapshot
+		}
+		out = append(out, snapshot.node.Clone())
+	}
+
+	// Forget the engines that left the cluster.
+	if len(s.snapshots) > len(engines) {
+		for id := range s.snapshots {
+			if _, ok := engines[id]; !ok {
+				delete(s.snapshots, id)
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,105 @@
+package swarm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func createSnapshotEngines(count, containers int) map[string]*cluster.Engine {
+	engines := make(map[string]*cluster.Engine, count)
+	for i := 0; i < count; i++ {
+		ID := fmt.Sprintf("node-%d", i)
+		engine := cluster.NewEngine(ID, 0, engOpts)
+		engine.Name = ID
+		engine.ID = ID
+		engine.Memory = 1024
+		engine.Cpus = 4
+		for j := 0; j < containers; j++ {
+			engine.AddContainer(&cluster.Container{
+				Container: types.Container{ID: fmt.Sprintf("%s-%d", ID, j)},
+				Config:    createPreemptionConfig(1),
+				Engine:    engine,
+			})
+		}
+		engines[ID] = engine
+	}
+	return engines
+}
+
+func TestNodeStore(t *testing.T) {
+	s := newNodeStore()
+	engines := createSnapshotEngines(2, 1)
+
+	nodes := s.Nodes(engines)
+	assert.Len(t, nodes, 2)
+	for _, n := range nodes {
+		assert.Len(t, n.Containers, 1)
+		assert.Equal(t, n.UsedMemory, int64(1))
+	}
+
+	// Changing a view doesn't change the snapshot.
+	n := nodes[0]
+	assert.NoError(t, n.AddContainer(&cluster.Container{
+		Container: types.Container{ID: "pending"},
+		Config:    createPreemptionConfig(2),
+	}))
+	assert.Len(t, n.Containers, 2)
+	assert.Equal(t, n.UsedMemory, int64(3))
+	for _, view := range s.Nodes(engines) {
+		assert.Len(t, view.Containers, 1)
+		assert.Equal(t, view.UsedMemory, int64(1))
+		assert.Nil(t, view.Container("pending"))
+	}
+
+	// Only the engine that changed is rebuilt.
+	unchanged := s.snapshots["node-1"].node
+	engine := engines["node-0"]
+	assert.NoError(t, engine.AddContainer(&cluster.Container{
+		Container: types.Container{ID: "node-0-new"},
+		Config:    createPreemptionConfig(4),
+		Engine:    engine,
+	}))
+	for _, view := range s.Nodes(engines) {
+		if view.ID == "node-0" {
+			assert.Len(t, view.Containers, 2)
+			assert.Equal(t, view.UsedMemory, int64(5))
+		} else {
+			assert.Len(t, view.Containers, 1)
+		}
+	}
+	assert.True(t, s.snapshots["node-1"].node == unchanged)
+
+	// Engines that left the cluster are forgotten.
+	delete(engines, "node-0")
+	assert.Len(t, s.Nodes(engines), 1)
+	assert.Len(t, s.snapshots, 1)
+
+	// A nil store builds the nodes every time.
+	var nilStore *nodeStore
+	assert.Len(t, nilStore.Nodes(engines), 1)
+}
+
+func benchmarkListNodes(b *testing.B, nodes *nodeStore) {
+	c := &Cluster{
+		engines:           createSnapshotEngines(100, 100),
+		pendingContainers: make(map[string]*pendingContainer),
+		nodes:             nodes,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.listNodes()
+	}
+}
+
+func BenchmarkListNodes(b *testing.B) {
+	benchmarkListNodes(b, nil)
+}
+
+func BenchmarkListNodesSnapshot(b *testing.B) {
+	benchmarkListNodes(b, newNodeStore())
+}
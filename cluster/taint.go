@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// TaintLabelPrefix prefixes the engine labels tainting a node, in the
+	// form taint.<key>=<value>:<effect> (ex. taint.gpu=true:NoSchedule).
+	TaintLabelPrefix = "taint."
+
+	// TaintNoSchedule keeps the containers not tolerating the taint away
+	// from the node.
+	TaintNoSchedule = "NoSchedule"
+	// TaintPreferNoSchedule makes the node less likely to be selected for
+	// the containers not tolerating the taint.
+	TaintPreferNoSchedule = "PreferNoSchedule"
+)
+
+// Taint repels the containers that don't tolerate it from a node.
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+// String returns the taint in the form key=value:effect.
+func (t Taint) String() string {
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// ParseTaint parses the value of a taint label (ex. true:NoSchedule) into a
+// taint with the given key.
+func ParseTaint(key, value string) (Taint, error) {
+	i := strings.LastIndex(value, ":")
+	if key == "" || i < 0 {
+		return Taint{}, fmt.Errorf("invalid taint %s=%s, expected <key>=<value>:<effect>", key, value)
+	}
+	taint := Taint{Key: key, Value: value[:i], Effect: value[i+1:]}
+	if !isTaintEffect(taint.Effect) {
+		return Taint{}, fmt.Errorf("invalid taint effect %q, expected %s or %s", taint.Effect, TaintNoSchedule, TaintPreferNoSchedule)
+	}
+	return taint, nil
+}
+
+// Taints returns the valid taints of a node, by key.
+func Taints(labels map[string]string) []Taint {
+	taints := []Taint{}
+	for key, value := range labels {
+		if !strings.HasPrefix(key, TaintLabelPrefix) {
+			continue
+		}
+		if taint, err := ParseTaint(strings.TrimPrefix(key, TaintLabelPrefix), value); err == nil {
+			taints = append(taints, taint)
+		}
+	}
+	sort.Sort(taintsByKey(taints))
+	return taints
+}
+
+type taintsByKey []Taint
+
+func (t taintsByKey) Len() int           { return len(t) }
+func (t taintsByKey) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t taintsByKey) Less(i, j int) bool { return t[i].Key < t[j].Key }
+
+// Toleration lets a container be scheduled on nodes with matching taints. An
+// empty value or effect matches any value or effect.
+type Toleration struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+// ParseToleration parses a toleration in the form key[=value][:effect] (ex.
+// gpu=true:NoSchedule or gpu).
+func ParseToleration(s string) (Toleration, error) {
+	toleration := Toleration{}
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		toleration.Effect = s[i+1:]
+		if !isTaintEffect(toleration.Effect) {
+			return Toleration{}, fmt.Errorf("invalid toleration effect %q, expected %s or %s", toleration.Effect, TaintNoSchedule, TaintPreferNoSchedule)
+		}
+		s = s[:i]
+	}
+	parts := strings.SplitN(s, "=", 2)
+	toleration.Key = parts[0]
+	if len(parts) == 2 {
+		toleration.Value = parts[1]
+	}
+	if toleration.Key == "" {
+		return Toleration{}, errors.New("invalid toleration, the key is empty")
+	}
+	return toleration, nil
+}
+
+// Tolerates returns true if the toleration matches the taint.
+func (t Toleration) Tolerates(taint Taint) bool {
+	return t.Key == taint.Key &&
+		(t.Value == "" || t.Value == taint.Value) &&
+		(t.Effect == "" || t.Effect == taint.Effect)
+}
+
+// Tolerated returns true if one of the tolerations matches the taint.
+func Tolerated(taint Taint, tolerations []Toleration) bool {
+	for _, toleration := range tolerations {
+		if toleration.Tolerates(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTaintEffect(effect string) bool {
+	return effect == TaintNoSchedule || effect == TaintPreferNoSchedule
+}
@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTaint(t *testing.T) {
+	taint, err := ParseTaint("gpu", "true:NoSchedule")
+	assert.NoError(t, err)
+	assert.Equal(t, taint, Taint{Key: "gpu", Value: "true", Effect: TaintNoSchedule})
+	assert.Equal(t, taint.String(), "gpu=true:NoSchedule")
+
+	taint, err = ParseTaint("edge", ":PreferNoSchedule")
+	assert.NoError(t, err)
+	assert.Equal(t, taint, Taint{Key: "edge", Effect: TaintPreferNoSchedule})
+
+	for _, value := range []string{"true", "true:Never", ""} {
+		_, err = ParseTaint("gpu", value)
+		assert.Error(t, err, value)
+	}
+	_, err = ParseTaint("", "true:NoSchedule")
+	assert.Error(t, err)
+
+	assert.Equal(t, Taints(map[string]string{
+		"taint.gpu":     "true:NoSchedule",
+		"taint.edge":    "yes:PreferNoSchedule",
+		"taint.invalid": "yes",
+		"storagedriver": "aufs",
+	}), []Taint{
+		{Key: "edge", Value: "yes", Effect: TaintPreferNoSchedule},
+		{Key: "gpu", Value: "true", Effect: TaintNoSchedule},
+	})
+}
+
+func TestToleration(t *testing.T) {
+	gpu := Taint{Key: "gpu", Value: "true", Effect: TaintNoSchedule}
+
+	for s, tolerates := range map[string]bool{
+		"gpu":                       true,
+		"gpu=true":                  true,
+		"gpu:NoSchedule":            true,
+		"gpu=true:NoSchedule":       true,
+		"gpu=false":                 false,
+		"gpu:PreferNoSchedule":      false,
+		"edge=true:NoSchedule":      false,
+		"gpu=true:PreferNoSchedule": false,
+	} {
+		toleration, err := ParseToleration(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, toleration.Tolerates(gpu), tolerates, s)
+	}
+
+	for _, s := range []string{"", "=true", "gpu:Never"} {
+		_, err := ParseToleration(s)
+		assert.Error(t, err, s)
+	}
+
+	assert.False(t, Tolerated(gpu, nil))
+	assert.True(t, Tolerated(gpu, []Toleration{{Key: "edge"}, {Key: "gpu"}}))
+
+	config := BuildContainerConfig(container.Config{Env: []string{"toleration:gpu=true:NoSchedule"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Empty(t, config.Env)
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, config.Tolerations(), []Toleration{{Key: "gpu", Value: "true", Effect: TaintNoSchedule}})
+
+	config = BuildContainerConfig(container.Config{Labels: map[string]string{SwarmLabelNamespace + ".tolerations": `["gpu", "edge:PreferNoSchedule"]`}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Equal(t, config.Tolerations(), []Toleration{{Key: "gpu"}, {Key: "edge", Effect: TaintPreferNoSchedule}})
+
+	config = BuildContainerConfig(container.Config{Env: []string{"toleration:gpu:Never"}}, container.HostConfig{}, network.NetworkingConfig{})
+	assert.Error(t, config.Validate())
+}
@@ -1,6 +1,10 @@
 package cluster
 
-import "github.com/docker/engine-api/types"
+import (
+	"strings"
+
+	"github.com/docker/engine-api/types"
+)
 
 // Volume is exported
 type Volume struct {
@@ -9,6 +13,25 @@ type Volume struct {
 	Engine *Engine
 }
 
+// IsLocal returns true if the volume only exists on its engine.
+func (volume *Volume) IsLocal() bool {
+	return volume.Scope == "local" || (volume.Scope == "" && volume.Driver == "local")
+}
+
+// ParseVolumeBind returns the named volume of a bind (ex. data:/data), and
+// the node it is prefixed with, if any (ex. node-1/data:/data). ok is false
+// for host paths and anonymous volumes.
+func ParseVolumeBind(bind string) (node, name string, ok bool) {
+	parts := strings.SplitN(bind, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.HasPrefix(parts[0], "/") || strings.HasPrefix(parts[0], ".") {
+		return "", "", false
+	}
+	if i := strings.Index(parts[0], "/"); i >= 0 {
+		return parts[0][:i], parts[0][i+1:], true
+	}
+	return "", parts[0], true
+}
+
 // Volumes represents an array of volumes
 type Volumes []*Volume
 
@@ -88,3 +88,29 @@ func TestGet(t *testing.T) {
 		assert.Equal(t, volumes.Get("t4"), volumes[3])
 	}
 }
+
+func TestParseVolumeBind(t *testing.T) {
+	node, name, ok := ParseVolumeBind("data:/data")
+	assert.True(t, ok)
+	assert.Equal(t, node, "")
+	assert.Equal(t, name, "data")
+
+	node, name, ok = ParseVolumeBind("node-1/data:/data:ro")
+	assert.True(t, ok)
+	assert.Equal(t, node, "node-1")
+	assert.Equal(t, name, "data")
+
+	for _, bind := range []string{"/data", "/tmp:/data", "./tmp:/data", ":/data"} {
+		_, _, ok = ParseVolumeBind(bind)
+		assert.False(t, ok, bind)
+	}
+}
+
+func TestEngineLocalBinds(t *testing.T) {
+	engine := &Engine{ID: "node-1-id", Name: "node-1"}
+
+	assert.Nil(t, engine.localBinds(nil))
+	binds := []string{"node-1/data:/data", "node-1-id/logs:/logs:ro", "node-2/data:/data2", "cache:/cache", "/tmp:/tmp"}
+	assert.Equal(t, engine.localBinds(binds), []string{"data:/data", "logs:/logs:ro", "node-2/data:/data2", "cache:/cache", "/tmp:/tmp"})
+	assert.Equal(t, binds[0], "node-1/data:/data")
+}
@@ -1,29 +1,73 @@
 package cluster
 
 import (
+	"strings"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
 )
 
+const (
+	// engine_connect is emitted before the engine is validated by the cluster.
+	// Wait for up to validationRetries*validationInterval for it to be healthy.
+	validationRetries  = 50
+	validationInterval = 200 * time.Millisecond
+
+	// A container with the on-container-failure policy is moved once it
+	// failed containerFailureThreshold times within containerFailureWindow
+	// on the same node, or as soon as it is OOM-killed.
+	containerFailureThreshold = 3
+	containerFailureWindow    = 10 * time.Minute
+
+	// maxRescheduleBackoff caps the delay between two attempts to reschedule
+	// a container.
+	maxRescheduleBackoff = 5 * time.Minute
+)
+
+// moveReason is the failure a container is moved away from.
+type moveReason int
+
+const (
+	nodeFailure moveReason = iota
+	nodeDrain
+	containerFailure
+)
+
+// constrainedCreator is implemented by the clusters able to schedule a
+// container with extra constraints, which are not stored in the container.
+type constrainedCreator interface {
+	CreateContainerWithConstraints(config *ContainerConfig, name string, constraints []string, authConfig *types.AuthConfig) (*Container, error)
+}
+
 // Watchdog listens to cluster events and handles container rescheduling
 type Watchdog struct {
 	sync.Mutex
-	cluster Cluster
+	cluster  Cluster
+	failures map[string][]time.Time
+	// moving are the containers being moved, by ID.
+	moving map[string]bool
 }
 
 // Handle handles cluster callbacks
 func (w *Watchdog) Handle(e *Event) error {
-	// Skip non-swarm events.
+	// Container failures are reported by the engines.
 	if e.From != "swarm" {
+		if e.Engine != nil && (e.Type == "container" || e.Type == "") && (e.Action == "die" || e.Status == "die") {
+			go w.handleContainerFailure(e.Engine, e.ID)
+		}
 		return nil
 	}
 
 	switch e.Status {
 	case "engine_connect", "engine_reconnect":
 		go w.removeDuplicateContainers(e.Engine)
+		go w.createGlobalContainers(e.Engine)
 	case "engine_disconnect":
 		go w.rescheduleContainers(e.Engine)
+	case "engine_drain":
+		go w.drainContainers(e.Engine)
 	}
 	return nil
 }
@@ -55,11 +99,62 @@ func (w *Watchdog) removeDuplicateContainers(e *Engine) {
 	}
 }
 
-// rescheduleContainers reschedules containers as soon as a node fails
-func (w *Watchdog) rescheduleContainers(e *Engine) {
+// createGlobalContainers creates the instances of global containers missing
+// on a node joining the cluster
+func (w *Watchdog) createGlobalContainers(e *Engine) {
+	// Until every engine is loaded, the instances running on the others
+	// are not known yet.
+	<-w.cluster.Loaded()
+	for i := 0; i < validationRetries && !e.IsHealthy(); i++ {
+		time.Sleep(validationInterval)
+	}
+	if !e.IsHealthy() {
+		log.Debugf("Node %s is not healthy, skipping creation of global containers", e.ID)
+		return
+	}
+
 	w.Lock()
 	defer w.Unlock()
 
+	// Find one instance of each global container, and check whether the
+	// engine already runs it.
+	instances := make(map[string]*Container)
+	for _, container := range w.cluster.Containers() {
+		globalID := container.Config.GlobalID()
+		if globalID == "" {
+			continue
+		}
+		if container.Engine.ID == e.ID {
+			instances[globalID] = nil
+		} else if _, ok := instances[globalID]; !ok {
+			instances[globalID] = container
+		}
+	}
+
+	for globalID, c := range instances {
+		if c == nil {
+			continue
+		}
+
+		log.Debugf("Creating global container %s on node %s", globalID, e.Name)
+		newContainer, err := w.cluster.CreateContainer(c.Config.Copy(), GlobalName(c.Info.Name, c.Engine.Name), nil)
+		if err != nil {
+			log.Warnf("Unable to create global container %s on node %s: %v", globalID, e.Name, err)
+			continue
+		}
+
+		log.Infof("Created global container %s on %s as %s", globalID, newContainer.Engine.Name, newContainer.ID)
+		if c.Info.State.Running {
+			if err := w.cluster.StartContainer(newContainer, nil); err != nil {
+				log.Errorf("Failed to start global container %s: %v", newContainer.ID, err)
+			}
+		}
+	}
+}
+
+// rescheduleContainers reschedules containers when a node fails, once their
+// grace period elapsed
+func (w *Watchdog) rescheduleContainers(e *Engine) {
 	log.Debugf("Node %s failed - rescheduling containers", e.ID)
 	for _, c := range e.Containers() {
 
@@ -69,29 +164,200 @@ func (w *Watchdog) rescheduleContainers(e *Engine) {
 			continue
 		}
 
-		// Remove the container from the dead engine. If we don't, then both
-		// the old and new one will show up in docker ps.
-		// We have to do this before calling `CreateContainer`, otherwise it
-		// will abort because the name is already taken.
-		c.Engine.removeContainer(c)
+		// The node may come back during the grace period.
+		w.scheduleMove(c, nodeFailure, c.Config.RescheduleGrace(), 1, func() bool {
+			return !e.IsHealthy()
+		})
+	}
+}
+
+// drainContainers moves containers away from a node under maintenance
+func (w *Watchdog) drainContainers(e *Engine) {
+	log.Debugf("Node %s is under maintenance - draining containers", e.ID)
+	for _, c := range e.Containers() {
+
+		// Only containers with a reschedule policy are allowed to move.
+		if !c.Config.HasReschedulePolicy("on-node-failure") {
+			log.Debugf("Skipping draining of %s based on rescheduling policies", c.ID)
+			continue
+		}
 
-		newContainer, err := w.cluster.CreateContainer(c.Config, c.Info.Name, nil)
+		w.scheduleMove(c, nodeDrain, 0, 1, func() bool {
+			return e.IsHealthy() && e.IsUnderMaintenance()
+		})
+	}
+}
 
+// handleContainerFailure moves a container with the on-container-failure
+// policy away from its node if it keeps failing there.
+func (w *Watchdog) handleContainerFailure(e *Engine, ID string) {
+	c := e.Containers().Get(ID)
+	if c == nil || c.Config == nil || !c.Config.HasReschedulePolicy("on-container-failure") {
+		return
+	}
+	if !w.containerFailed(c, time.Now()) {
+		return
+	}
+
+	log.Debugf("Container %s keeps failing on node %s - rescheduling it", c.ID, e.Name)
+	w.scheduleMove(c, containerFailure, c.Config.RescheduleGrace(), 1, func() bool {
+		return e.Containers().Get(c.ID) != nil
+	})
+}
+
+// containerFailed records a failure of the container, and returns true if
+// the container should be moved to another node.
+func (w *Watchdog) containerFailed(c *Container, now time.Time) bool {
+	state := c.Info.State
+	if state == nil || (state.ExitCode == 0 && !state.OOMKilled) {
+		return false
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if w.failures == nil {
+		w.failures = make(map[string][]time.Time)
+	}
+	failures := []time.Time{}
+	for _, t := range w.failures[c.ID] {
+		if now.Sub(t) < containerFailureWindow {
+			failures = append(failures, t)
+		}
+	}
+	failures = append(failures, now)
+
+	if state.OOMKilled || len(failures) >= containerFailureThreshold {
+		delete(w.failures, c.ID)
+		return true
+	}
+	w.failures[c.ID] = failures
+	return false
+}
+
+// rescheduleBackoff returns the delay before the given attempt to reschedule
+// a container, attempts starting at 1.
+func rescheduleBackoff(backoff time.Duration, attempt int) time.Duration {
+	for i := 2; i < attempt && backoff < maxRescheduleBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRescheduleBackoff {
+		return maxRescheduleBackoff
+	}
+	return backoff
+}
+
+// scheduleMove moves the container after delay if failed still returns true
+// by then. Failed attempts are retried with an exponential backoff, up to the
+// maximum number of attempts of the container.
+func (w *Watchdog) scheduleMove(c *Container, reason moveReason, delay time.Duration, attempt int, failed func() bool) {
+	time.AfterFunc(delay, func() {
+		// The lock isn't held during the move, which talks to the engines.
+		// A container already being moved is skipped instead.
+		w.Lock()
+		if !failed() {
+			w.Unlock()
+			log.Debugf("Skipping rescheduling of %s, the failure is over", c.ID)
+			return
+		}
+		if w.moving[c.ID] {
+			w.Unlock()
+			log.Debugf("Skipping rescheduling of %s, it is already being moved", c.ID)
+			return
+		}
+		if w.moving == nil {
+			w.moving = make(map[string]bool)
+		}
+		w.moving[c.ID] = true
+		w.Unlock()
+
+		defer func() {
+			w.Lock()
+			delete(w.moving, c.ID)
+			w.Unlock()
+		}()
+
+		newContainer, err := w.moveContainer(c, reason)
 		if err != nil {
-			log.Errorf("Failed to reschedule container %s: %v", c.ID, err)
-			// add the container back, so we can retry later
-			c.Engine.AddContainer(c)
-		} else {
-			log.Infof("Rescheduled container %s from %s to %s as %s", c.ID, c.Engine.Name, newContainer.Engine.Name, newContainer.ID)
-			if c.Info.State.Running {
-				log.Infof("Container %s was running, starting container %s", c.ID, newContainer.ID)
-				if err := w.cluster.StartContainer(newContainer, nil); err != nil {
-					log.Errorf("Failed to start rescheduled container %s: %v", newContainer.ID, err)
-				}
+			if attempt >= c.Config.RescheduleMaxAttempts() {
+				log.Errorf("Giving up rescheduling container %s after %d attempts", c.ID, attempt)
+				return
 			}
+			backoff := rescheduleBackoff(c.Config.RescheduleBackoff(), attempt+1)
+			log.Warnf("Retrying to reschedule container %s in %s", c.ID, backoff)
+			w.scheduleMove(c, reason, backoff, attempt+1, failed)
+			return
 		}
+
+		// The failed node may have come back during the move, after its
+		// duplicate containers were removed.
+		if reason == nodeFailure {
+			if c.Engine.IsHealthy() {
+				w.removeDuplicateContainers(c.Engine)
+			}
+			return
+		}
+
+		// Unlike a failed node, the engine is still reachable: remove the
+		// original container so it doesn't show up as a duplicate, keeping
+		// its volumes, and give its name to the copy.
+		if err := c.Engine.RemoveContainer(c, true, false); err != nil {
+			log.Errorf("Failed to remove rescheduled container %s on node %s: %v", c.ID, c.Engine.Name, err)
+			return
+		}
+		if name := strings.TrimPrefix(c.Info.Name, "/"); name != "" {
+			if err := w.cluster.RenameContainer(newContainer, name); err != nil {
+				log.Errorf("Failed to rename rescheduled container %s to %s: %v", newContainer.ID, name, err)
+			}
+		}
+	})
+}
+
+// moveContainer creates a copy of the container on another node, and starts it
+// if the original container was running or failed.
+func (w *Watchdog) moveContainer(c *Container, reason moveReason) (*Container, error) {
+	// Remove the container from its engine. If we don't, then both
+	// the old and new one will show up in docker ps.
+	// We have to do this before calling `CreateContainer`, otherwise it
+	// will abort because the name is already taken.
+	c.Engine.removeContainer(c)
+
+	// When the original container still exists, the copy takes a generated
+	// name until the original is removed.
+	name := c.Info.Name
+	if reason != nodeFailure {
+		name = ""
+	}
+
+	// The node of a failed container is still schedulable: avoid it, or
+	// the container may land back on it. The constraint only applies to this
+	// move, and isn't stored in the copy.
+	var (
+		newContainer *Container
+		err          error
+	)
+	creator, ok := w.cluster.(constrainedCreator)
+	if reason == containerFailure && ok {
+		newContainer, err = creator.CreateContainerWithConstraints(c.Config, name, []string{"node!=~" + c.Engine.Name}, nil)
+	} else {
+		newContainer, err = w.cluster.CreateContainer(c.Config, name, nil)
+	}
+
+	if err != nil {
+		log.Errorf("Failed to reschedule container %s: %v", c.ID, err)
+		// add the container back, so we can retry later
+		c.Engine.AddContainer(c)
+		return nil, err
 	}
 
+	log.Infof("Rescheduled container %s from %s to %s as %s", c.ID, c.Engine.Name, newContainer.Engine.Name, newContainer.ID)
+	if c.Info.State.Running || reason == containerFailure {
+		log.Infof("Container %s was running, starting container %s", c.ID, newContainer.ID)
+		if err := w.cluster.StartContainer(newContainer, nil); err != nil {
+			log.Errorf("Failed to start rescheduled container %s: %v", newContainer.ID, err)
+		}
+	}
+	return newContainer, nil
 }
 
 // NewWatchdog creates a new watchdog
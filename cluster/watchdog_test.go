@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// moveCluster records the containers created by the watchdog.
+type moveCluster struct {
+	Cluster
+	configs     []*ContainerConfig
+	constraints [][]string
+}
+
+func (c *moveCluster) CreateContainerWithConstraints(config *ContainerConfig, name string, constraints []string, authConfig *types.AuthConfig) (*Container, error) {
+	c.configs = append(c.configs, config)
+	c.constraints = append(c.constraints, constraints)
+	return &Container{Container: types.Container{ID: "copy"}, Config: config, Engine: NewEngine("node-2", 0, &EngineOpts{})}, nil
+}
+
+func (c *moveCluster) CreateContainer(config *ContainerConfig, name string, authConfig *types.AuthConfig) (*Container, error) {
+	return c.CreateContainerWithConstraints(config, name, nil, authConfig)
+}
+
+func (c *moveCluster) StartContainer(container *Container, hostConfig *dockerclient.HostConfig) error {
+	return nil
+}
+
+func TestRescheduleBackoff(t *testing.T) {
+	assert.Equal(t, rescheduleBackoff(10*time.Second, 2), 10*time.Second)
+	assert.Equal(t, rescheduleBackoff(10*time.Second, 3), 20*time.Second)
+	assert.Equal(t, rescheduleBackoff(10*time.Second, 4), 40*time.Second)
+	assert.Equal(t, rescheduleBackoff(10*time.Second, 100), maxRescheduleBackoff)
+	assert.Equal(t, rescheduleBackoff(time.Hour, 2), maxRescheduleBackoff)
+}
+
+func TestContainerFailed(t *testing.T) {
+	w := &Watchdog{}
+	state := &types.ContainerState{}
+	c := &Container{
+		Container: types.Container{ID: "c1"},
+		Info:      types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: state}},
+	}
+	now := time.Now()
+
+	// A clean exit is not a failure.
+	assert.False(t, w.containerFailed(c, now))
+
+	// The container moves once it failed often enough within the window.
+	state.ExitCode = 1
+	assert.False(t, w.containerFailed(c, now.Add(-containerFailureWindow)))
+	assert.False(t, w.containerFailed(c, now))
+	assert.False(t, w.containerFailed(c, now))
+	assert.True(t, w.containerFailed(c, now))
+	assert.False(t, w.containerFailed(c, now))
+
+	// An OOM kill moves the container right away.
+	state.ExitCode = 137
+	state.OOMKilled = true
+	assert.True(t, w.containerFailed(c, now))
+}
+
+func TestMoveContainerAvoidsNode(t *testing.T) {
+	engine := NewEngine("node-1", 0, &EngineOpts{})
+	engine.Name = "node-1"
+	config := BuildContainerConfig(containertypes.Config{Image: "busybox"}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	c := &Container{
+		Container: types.Container{ID: "c1"},
+		Config:    config,
+		Engine:    engine,
+		Info:      types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{}}},
+	}
+	cluster := &moveCluster{}
+	w := &Watchdog{cluster: cluster}
+
+	// The failed node is only avoided for the move, the copy doesn't keep
+	// the constraint.
+	_, err := w.moveContainer(c, containerFailure)
+	assert.NoError(t, err)
+	assert.Equal(t, cluster.constraints[0], []string{"node!=~node-1"})
+	assert.Empty(t, cluster.configs[0].Constraints())
+
+	_, err = w.moveContainer(c, nodeDrain)
+	assert.NoError(t, err)
+	assert.Empty(t, cluster.constraints[1])
+}
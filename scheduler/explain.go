@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/docker/swarm/scheduler/strategy"
+)
+
+// Explain runs the scheduling of a container without side effects, and
+// reports which filter rejected each node, or how the strategy ranked it.
+// Each filter runs once per pass, like in SelectNodesForContainer.
+func (s *Scheduler) Explain(nodes []*node.Node, config *cluster.ContainerConfig) *cluster.ScheduleExplanation {
+	// Mimic SelectNodesForContainer: soft constraints are only ignored when
+	// no node satisfies them.
+	explanation, err := s.explain(nodes, config, true)
+	if err != nil {
+		explanation, err = s.explain(nodes, config, false)
+	}
+
+	if err != nil {
+		explanation.Error = err.Error()
+	}
+	return explanation
+}
+
+// explain explains one pass of the scheduling, and returns the error
+// SelectNodesForContainer would fail with.
+func (s *Scheduler) explain(nodes []*node.Node, config *cluster.ContainerConfig, soft bool) (*cluster.ScheduleExplanation, error) {
+	explanation := &cluster.ScheduleExplanation{
+		Strategy:        s.strategy.Name(),
+		SoftConstraints: soft,
+		Nodes:           make([]*cluster.NodeExplanation, 0, len(nodes)),
+	}
+	explained := make(map[string]*cluster.NodeExplanation, len(nodes))
+	for _, n := range nodes {
+		ne := &cluster.NodeExplanation{ID: n.ID, Name: n.Name}
+		explanation.Nodes = append(explanation.Nodes, ne)
+		explained[n.ID] = ne
+	}
+	defer sort.Sort(nodeExplanations(explanation.Nodes))
+
+	candidates := nodes
+	for _, f := range s.filters {
+		var (
+			accepted []*node.Node
+			reason   string
+			err      error
+		)
+		if explainer, ok := f.(filter.Explainer); ok {
+			accepted, reason, err = explainer.Explain(config, candidates, soft)
+		} else {
+			accepted, err = f.Filter(config, candidates, soft)
+		}
+		if err != nil {
+			accepted = nil
+		}
+		if reason == "" {
+			reason = rejectReason(f, config, err)
+		}
+		for _, n := range rejected(candidates, accepted) {
+			explained[n.ID].Filter = f.Name()
+			explained[n.ID].Reason = reason
+		}
+		candidates = accepted
+		if err != nil {
+			return explanation, filter.RejectionError(s.filters, config, f, err)
+		}
+	}
+	if len(candidates) == 0 {
+		return explanation, errNoNodeAvailable
+	}
+
+	var weights map[string]int64
+	if weighted, ok := s.strategy.(strategy.WeightedStrategy); ok {
+		weights, _ = weighted.Weigh(config, candidates)
+	}
+
+	ranked, rankErr := s.rank(config, nodes, append([]*node.Node{}, candidates...))
+	if rankErr != nil {
+		ranked = nil
+	}
+	for i, n := range ranked {
+		explained[n.ID].Rank = i + 1
+		if weight, ok := weights[n.ID]; ok {
+			explained[n.ID].Weight = &weight
+		}
+	}
+	// The strategy skips the nodes without enough resources.
+	for _, n := range rejected(candidates, ranked) {
+		explained[n.ID].Reason = strategy.ErrNoResourcesAvailable.Error()
+	}
+	return explanation, rankErr
+}
+
+// rejected returns the nodes which are not part of accepted.
+func rejected(nodes, accepted []*node.Node) []*node.Node {
+	ids := make(map[string]struct{}, len(accepted))
+	for _, n := range accepted {
+		ids[n.ID] = struct{}{}
+	}
+
+	out := []*node.Node{}
+	for _, n := range nodes {
+		if _, ok := ids[n.ID]; !ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// rejectReason explains why a filter rejected nodes. Filters report the
+// unsatisfied condition when they reject every node they are given, otherwise
+// the conditions they check are used.
+func rejectReason(f filter.Filter, config *cluster.ContainerConfig, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if list, err := f.GetFilters(config); err == nil && len(list) > 0 {
+		return strings.Join(list, ", ")
+	}
+	return "rejected by the " + f.Name() + " filter"
+}
+
+// nodeExplanations sorts ranked nodes first, by rank, then rejected nodes by
+// name.
+type nodeExplanations []*cluster.NodeExplanation
+
+func (n nodeExplanations) Len() int      { return len(n) }
+func (n nodeExplanations) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n nodeExplanations) Less(i, j int) bool {
+	if n[i].Rank != 0 && n[j].Rank != 0 {
+		return n[i].Rank < n[j].Rank
+	}
+	if n[i].Rank != 0 || n[j].Rank != 0 {
+		return n[i].Rank != 0
+	}
+	return n[i].Name < n[j].Name
+}
@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"testing"
+
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+func createExplainNodes() []*node.Node {
+	return []*node.Node{
+		{
+			ID:              "node-0-id",
+			Name:            "node-0-name",
+			TotalMemory:     2 * 1024 * 1024 * 1024,
+			TotalCpus:       2,
+			HealthIndicator: 100,
+			Labels:          map[string]string{"group": "1"},
+		},
+		{
+			ID:              "node-1-id",
+			Name:            "node-1-name",
+			TotalMemory:     2 * 1024 * 1024 * 1024,
+			TotalCpus:       2,
+			UsedMemory:      1024 * 1024 * 1024,
+			HealthIndicator: 100,
+			Labels:          map[string]string{"group": "1"},
+		},
+		{
+			ID:              "node-2-id",
+			Name:            "node-2-name",
+			TotalMemory:     2 * 1024 * 1024 * 1024,
+			TotalCpus:       2,
+			HealthIndicator: 100,
+			Labels:          map[string]string{"group": "2"},
+		},
+		{
+			ID:     "node-3-id",
+			Name:   "node-3-name",
+			Labels: map[string]string{"group": "1"},
+		},
+	}
+}
+
+func TestExplain(t *testing.T) {
+	s := Scheduler{
+		strategy: &strategy.SpreadPlacementStrategy{},
+		filters:  []filter.Filter{&filter.HealthFilter{}, &filter.ConstraintFilter{}},
+	}
+	config := cluster.BuildContainerConfig(containertypes.Config{
+		Env: []string{"constraint:group==1"},
+	}, containertypes.HostConfig{
+		Resources: containertypes.Resources{
+			Memory: 512 * 1024 * 1024,
+		},
+	}, networktypes.NetworkingConfig{})
+
+	explanation := s.Explain(createExplainNodes(), config)
+	assert.Equal(t, explanation.Strategy, "spread")
+	assert.True(t, explanation.SoftConstraints)
+	assert.Empty(t, explanation.Error)
+	assert.Len(t, explanation.Nodes, 4)
+
+	// Ranked nodes come first.
+	assert.Equal(t, explanation.Nodes[0].ID, "node-0-id")
+	assert.Equal(t, explanation.Nodes[0].Rank, 1)
+	assert.NotNil(t, explanation.Nodes[0].Weight)
+	assert.Empty(t, explanation.Nodes[0].Filter)
+	assert.Equal(t, explanation.Nodes[1].ID, "node-1-id")
+	assert.Equal(t, explanation.Nodes[1].Rank, 2)
+	assert.True(t, *explanation.Nodes[1].Weight > *explanation.Nodes[0].Weight)
+
+	// Then rejected nodes, with the filter which rejected them.
+	assert.Equal(t, explanation.Nodes[2].ID, "node-2-id")
+	assert.Equal(t, explanation.Nodes[2].Rank, 0)
+	assert.Nil(t, explanation.Nodes[2].Weight)
+	assert.Equal(t, explanation.Nodes[2].Filter, "constraint")
+	assert.Contains(t, explanation.Nodes[2].Reason, "group==1")
+	assert.Equal(t, explanation.Nodes[3].ID, "node-3-id")
+	assert.Equal(t, explanation.Nodes[3].Filter, "health")
+	assert.Equal(t, explanation.Nodes[3].Reason, "rejected by the health filter")
+}
+
+func TestExplainSoftConstraints(t *testing.T) {
+	s := Scheduler{
+		strategy: &strategy.SpreadPlacementStrategy{},
+		filters:  []filter.Filter{&filter.HealthFilter{}, &filter.ConstraintFilter{}},
+	}
+	config := cluster.BuildContainerConfig(containertypes.Config{
+		Env: []string{"constraint:group==~3"},
+	}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+
+	explanation := s.Explain(createExplainNodes(), config)
+	assert.False(t, explanation.SoftConstraints)
+	assert.Empty(t, explanation.Error)
+	assert.Equal(t, explanation.Nodes[0].Rank, 1)
+	assert.Equal(t, explanation.Nodes[1].Rank, 2)
+	assert.Equal(t, explanation.Nodes[2].Rank, 3)
+	assert.Equal(t, explanation.Nodes[3].Filter, "health")
+}
+
+func TestExplainNoResources(t *testing.T) {
+	s := Scheduler{
+		strategy: &strategy.BinpackPlacementStrategy{},
+		filters:  []filter.Filter{&filter.HealthFilter{}},
+	}
+	config := cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{
+		Resources: containertypes.Resources{
+			Memory: 2 * 1024 * 1024 * 1024,
+		},
+	}, networktypes.NetworkingConfig{})
+
+	explanation := s.Explain(createExplainNodes(), config)
+	assert.Empty(t, explanation.Error)
+	assert.Equal(t, explanation.Nodes[0].ID, "node-0-id")
+	assert.Equal(t, explanation.Nodes[0].Rank, 1)
+	assert.Equal(t, explanation.Nodes[1].ID, "node-2-id")
+	assert.Equal(t, explanation.Nodes[1].Rank, 2)
+	assert.Equal(t, explanation.Nodes[2].ID, "node-1-id")
+	assert.Equal(t, explanation.Nodes[2].Rank, 0)
+	assert.Empty(t, explanation.Nodes[2].Filter)
+	assert.Equal(t, explanation.Nodes[2].Reason, strategy.ErrNoResourcesAvailable.Error())
+
+	// No node is big enough.
+	config.HostConfig.Memory = 4 * 1024 * 1024 * 1024
+	explanation = s.Explain(createExplainNodes(), config)
+	assert.Equal(t, explanation.Error, strategy.ErrNoResourcesAvailable.Error())
+	for _, n := range explanation.Nodes {
+		assert.Equal(t, n.Rank, 0)
+		assert.NotEmpty(t, n.Reason)
+	}
+}
+
+// countingFilter counts the calls to a filter.
+type countingFilter struct {
+	*filter.ConstraintFilter
+	calls int
+}
+
+func (f *countingFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, soft bool) ([]*node.Node, error) {
+	f.calls++
+	return f.ConstraintFilter.Filter(config, nodes, soft)
+}
+
+func TestExplainRunsFiltersOnce(t *testing.T) {
+	counting := &countingFilter{ConstraintFilter: &filter.ConstraintFilter{}}
+	s := Scheduler{
+		strategy: &strategy.SpreadPlacementStrategy{},
+		filters:  []filter.Filter{&filter.HealthFilter{}, counting},
+	}
+	config := cluster.BuildContainerConfig(containertypes.Config{
+		Env: []string{"constraint:group==3"},
+	}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+
+	// The error is the one of the creation, without filtering again.
+	explanation := s.Explain(createExplainNodes(), config)
+	assert.Equal(t, counting.calls, 2)
+	_, err := s.SelectNodesForContainer(createExplainNodes(), config)
+	assert.Equal(t, explanation.Error, err.Error())
+}
@@ -26,50 +26,20 @@ func (f *AffinityFilter) Filter(config *cluster.ContainerConfig, nodes []*node.N
 	}
 
 	for _, affinity := range affinities {
-		if !soft && affinity.isSoft {
+		// Preferences are taken into account by the strategy.
+		if (!soft && affinity.isSoft) || affinity.weight > 0 {
 			continue
 		}
-		log.Debugf("matching affinity: %s%s%s (soft=%t)", affinity.key, OPERATORS[affinity.operator], affinity.value, affinity.isSoft)
+		log.Debugf("matching affinity: %s (soft=%t)", affinity.String(), affinity.isSoft)
 
 		candidates := []*node.Node{}
 		for _, node := range nodes {
-			switch affinity.key {
-			case "container":
-				containers := []string{}
-				for _, container := range node.Containers {
-					if len(container.Names) > 0 {
-						containers = append(containers, container.ID, strings.TrimPrefix(container.Names[0], "/"))
-					}
-				}
-				if affinity.Match(containers...) {
-					candidates = append(candidates, node)
-				}
-			case "image":
-				images := []string{}
-				for _, image := range node.Images {
-					images = append(images, image.ID)
-					images = append(images, image.RepoTags...)
-					for _, tag := range image.RepoTags {
-						repo, _ := cluster.ParseRepositoryTag(tag)
-						images = append(images, repo)
-					}
-				}
-				if affinity.Match(images...) {
-					candidates = append(candidates, node)
-				}
-			default:
-				labels := []string{}
-				for _, container := range node.Containers {
-					labels = append(labels, container.Labels[affinity.key])
-				}
-				if affinity.Match(labels...) {
-					candidates = append(candidates, node)
-				}
-
+			if affinity.MatchAny(func(affinity *expr) bool { return matchAffinity(affinity, node) }) {
+				candidates = append(candidates, node)
 			}
 		}
 		if len(candidates) == 0 {
-			return nil, fmt.Errorf("unable to find a node that satisfies the affinity %s%s%s", affinity.key, OPERATORS[affinity.operator], affinity.value)
+			return nil, fmt.Errorf("unable to find a node that satisfies the affinity %s", affinity.String())
 		}
 		nodes = candidates
 	}
@@ -77,6 +47,36 @@ func (f *AffinityFilter) Filter(config *cluster.ContainerConfig, nodes []*node.N
 	return nodes, nil
 }
 
+func matchAffinity(affinity *expr, node *node.Node) bool {
+	switch affinity.key {
+	case "container":
+		containers := []string{}
+		for _, container := range node.Containers {
+			if len(container.Names) > 0 {
+				containers = append(containers, container.ID, strings.TrimPrefix(container.Names[0], "/"))
+			}
+		}
+		return affinity.Match(containers...)
+	case "image":
+		images := []string{}
+		for _, image := range node.Images {
+			images = append(images, image.ID)
+			images = append(images, image.RepoTags...)
+			for _, tag := range image.RepoTags {
+				repo, _ := cluster.ParseRepositoryTag(tag)
+				images = append(images, repo)
+			}
+		}
+		return affinity.Match(images...)
+	default:
+		labels := []string{}
+		for _, container := range node.Containers {
+			labels = append(labels, container.Labels[affinity.key])
+		}
+		return affinity.Match(labels...)
+	}
+}
+
 // GetFilters returns a list of the affinities found in the container config.
 func (f *AffinityFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
 	allAffinities := []string{}
@@ -85,7 +85,7 @@ func (f *AffinityFilter) GetFilters(config *cluster.ContainerConfig) ([]string,
 		return nil, err
 	}
 	for _, affinity := range affinities {
-		allAffinities = append(allAffinities, fmt.Sprintf("%s%s%s (soft=%t)", affinity.key, OPERATORS[affinity.operator], affinity.value, affinity.isSoft))
+		allAffinities = append(allAffinities, fmt.Sprintf("%s (soft=%t)", affinity.String(), affinity.isSoft))
 	}
 	return allAffinities, nil
 }
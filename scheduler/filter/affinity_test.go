@@ -262,3 +262,60 @@ func TestAffinityFilterLabels(t *testing.T) {
 	assert.Len(t, result, 1)
 	assert.Equal(t, result[0], nodes[0])
 }
+
+func TestAffinityFilterSetAndOr(t *testing.T) {
+	var (
+		f     = AffinityFilter{}
+		nodes = []*node.Node{
+			{
+				ID:   "node-0-id",
+				Name: "node-0-name",
+				Containers: []*cluster.Container{
+					{Container: types.Container{
+						ID:    "container-n0-0-id",
+						Names: []string{"/redis"},
+					}},
+				},
+				Images: []*cluster.Image{{Image: types.Image{
+					ID:       "image-0-id",
+					RepoTags: []string{"redis:3"},
+				}}},
+			},
+			{
+				ID:   "node-1-id",
+				Name: "node-1-name",
+				Containers: []*cluster.Container{
+					{Container: types.Container{
+						ID:    "container-n1-0-id",
+						Names: []string{"/memcached"},
+					}},
+				},
+				Images: []*cluster.Image{{Image: types.Image{
+					ID:       "image-1-id",
+					RepoTags: []string{"nginx:latest"},
+				}}},
+			},
+			{
+				ID:   "node-2-id",
+				Name: "node-2-name",
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"affinity:container in (redis, memcached)"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"affinity:image notin (redis, nginx)"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[2])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"affinity:image==redis || container==memcached"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, result[0], nodes[0])
+	assert.Equal(t, result[1], nodes[1])
+}
@@ -0,0 +1,143 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// CapabilityFilter only schedules containers on the nodes whose engine has
+// the volume driver, log driver, network driver and runtime plugins they use.
+// The drivers and runtimes compiled in the engines are not checked, and nodes
+// whose engine doesn't advertise its drivers or runtimes are not filtered.
+type CapabilityFilter struct {
+}
+
+// capability is a driver or runtime required by a container, advertised by
+// the nodes in the comma separated list of an engine label.
+type capability struct {
+	label string
+	value string
+}
+
+func (c capability) String() string {
+	return fmt.Sprintf("%s=%s", c.label, c.value)
+}
+
+// Name returns the name of the filter
+func (f *CapabilityFilter) Name() string {
+	return "capability"
+}
+
+// Filter is exported
+func (f *CapabilityFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, _ bool) ([]*node.Node, error) {
+	if len(nodes) == 0 {
+		return nodes, nil
+	}
+
+	capabilities := f.capabilities(config, nodes)
+	if len(capabilities) == 0 {
+		return nodes, nil
+	}
+
+	candidates := []*node.Node{}
+	for _, node := range nodes {
+		if f.check(capabilities, node) {
+			candidates = append(candidates, node)
+		}
+	}
+
+	if len(candidates) == 0 {
+		list := []string{}
+		for _, capability := range capabilities {
+			list = append(list, capability.String())
+		}
+		return nil, fmt.Errorf("unable to find a node with the capabilities: %s", strings.Join(list, " "))
+	}
+
+	return candidates, nil
+}
+
+// GetFilters returns the driver and runtime plugins requested in the
+// container config.
+func (f *CapabilityFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	capabilities := []string{}
+	if driver := config.HostConfig.VolumeDriver; isPlugin(cluster.VolumeDriversLabel, driver) {
+		capabilities = append(capabilities, fmt.Sprintf("--volume-driver=%s", driver))
+	}
+	if driver := config.HostConfig.LogConfig.Type; isPlugin(cluster.LogDriversLabel, driver) {
+		capabilities = append(capabilities, fmt.Sprintf("--log-driver=%s", driver))
+	}
+	if runtime := config.HostConfig.Runtime; isPlugin(cluster.RuntimesLabel, runtime) {
+		capabilities = append(capabilities, fmt.Sprintf("--runtime=%s", runtime))
+	}
+	for _, network := range containerNetworks(config) {
+		capabilities = append(capabilities, fmt.Sprintf("--net=%s", network))
+	}
+	return capabilities, nil
+}
+
+// capabilities returns the plugins required by the container. The driver of
+// its networks is looked up on the nodes.
+func (f *CapabilityFilter) capabilities(config *cluster.ContainerConfig, nodes []*node.Node) []capability {
+	capabilities := []capability{}
+	if driver := config.HostConfig.VolumeDriver; isPlugin(cluster.VolumeDriversLabel, driver) {
+		capabilities = append(capabilities, capability{cluster.VolumeDriversLabel, driver})
+	}
+	if driver := config.HostConfig.LogConfig.Type; isPlugin(cluster.LogDriversLabel, driver) {
+		capabilities = append(capabilities, capability{cluster.LogDriversLabel, driver})
+	}
+	if runtime := config.HostConfig.Runtime; isPlugin(cluster.RuntimesLabel, runtime) {
+		capabilities = append(capabilities, capability{cluster.RuntimesLabel, runtime})
+	}
+
+	if names := containerNetworks(config); len(names) > 0 {
+		networks := cluster.Networks{}
+		for _, n := range nodes {
+			networks = append(networks, n.Networks...)
+		}
+		for _, name := range names {
+			if network := networks.Get(name); network != nil && isPlugin(cluster.NetworkDriversLabel, network.Driver) {
+				capabilities = append(capabilities, capability{cluster.NetworkDriversLabel, network.Driver})
+			}
+		}
+	}
+	return capabilities
+}
+
+// Ensure that the engine of the node has all the capabilities it advertises.
+func (f *CapabilityFilter) check(capabilities []capability, n *node.Node) bool {
+	for _, capability := range capabilities {
+		if n.Labels[capability.label] != "" && !cluster.HasCapability(n.Labels, capability.label, capability.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlugin returns true if the driver or runtime is set and provided by a
+// plugin.
+func isPlugin(label, value string) bool {
+	return value != "" && !cluster.IsBuiltinCapability(label, value)
+}
+
+// containerNetworks returns the user defined networks the container connects
+// to.
+func containerNetworks(config *cluster.ContainerConfig) []string {
+	networks := []string{}
+	mode := config.HostConfig.NetworkMode
+	if mode != "" && mode.IsUserDefined() {
+		networks = append(networks, string(mode))
+	}
+	endpoints := []string{}
+	for name := range config.NetworkingConfig.EndpointsConfig {
+		if name != string(mode) {
+			endpoints = append(endpoints, name)
+		}
+	}
+	sort.Strings(endpoints)
+	return append(networks, endpoints...)
+}
@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityFilter(t *testing.T) {
+	var (
+		f     = CapabilityFilter{}
+		nodes = []*node.Node{
+			{
+				ID:   "node-0-id",
+				Name: "node-0-name",
+				Labels: map[string]string{
+					cluster.VolumeDriversLabel:  "local,rexray",
+					cluster.NetworkDriversLabel: "bridge,host,null,overlay,weave",
+					cluster.LogDriversLabel:     "fluentd,json-file,loggly",
+					cluster.RuntimesLabel:       "runc",
+				},
+				Networks: cluster.Networks{
+					{NetworkResource: types.NetworkResource{ID: "net-weave", Name: "weavenet", Driver: "weave", Scope: "global"}},
+				},
+			},
+			{
+				ID:   "node-1-id",
+				Name: "node-1-name",
+				Labels: map[string]string{
+					cluster.VolumeDriversLabel:  "local",
+					cluster.NetworkDriversLabel: "bridge,host,null,overlay",
+					cluster.LogDriversLabel:     "json-file",
+					cluster.RuntimesLabel:       "nvidia,runc",
+				},
+				Networks: cluster.Networks{
+					{NetworkResource: types.NetworkResource{ID: "net-weave", Name: "weavenet", Driver: "weave", Scope: "global"}},
+				},
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	// The default drivers don't filter anything.
+	config := &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{VolumeDriver: "local", NetworkMode: "bridge"}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{VolumeDriver: "rexray"}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{LogConfig: containertypes.LogConfig{Type: "loggly"}}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	// The log drivers built into the engines depend on their OS, and are
+	// checked too.
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{LogConfig: containertypes.LogConfig{Type: "fluentd"}}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	// The other drivers compiled in the engines are not.
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{VolumeDriver: "local", Runtime: "runc"}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{Runtime: "nvidia"}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[1:])
+
+	// The driver of the networks is looked up on the nodes.
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{NetworkMode: "weavenet"}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	config = &cluster.ContainerConfig{NetworkingConfig: networktypes.NetworkingConfig{
+		EndpointsConfig: map[string]*networktypes.EndpointSettings{"weavenet": {}},
+	}}
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	// No node has all the capabilities.
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{VolumeDriver: "rexray", Runtime: "nvidia"}}
+	_, err = f.Filter(config, nodes, true)
+	assert.Error(t, err)
+
+	config = &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{VolumeDriver: "flocker"}}
+	_, err = f.Filter(config, nodes, true)
+	assert.Error(t, err)
+
+	// Nodes which don't advertise their drivers and runtimes are not filtered.
+	old := &node.Node{ID: "node-2-id", Name: "node-2-name", Labels: map[string]string{}}
+	result, err = f.Filter(config, append(nodes, old), true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []*node.Node{old})
+}
+
+func TestCapabilityFilterGetFilters(t *testing.T) {
+	f := CapabilityFilter{}
+	config := &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{
+		VolumeDriver: "rexray",
+		LogConfig:    containertypes.LogConfig{Type: "fluentd"},
+		Runtime:      "runc",
+		NetworkMode:  "weavenet",
+	}}
+	capabilities, err := f.GetFilters(config)
+	assert.NoError(t, err)
+	assert.Equal(t, capabilities, []string{"--volume-driver=rexray", "--log-driver=fluentd", "--net=weavenet"})
+}
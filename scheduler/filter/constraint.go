@@ -25,33 +25,36 @@ func (f *ConstraintFilter) Filter(config *cluster.ContainerConfig, nodes []*node
 	}
 
 	for _, constraint := range constraints {
-		if !soft && constraint.isSoft {
+		// Preferences are taken into account by the strategy.
+		if (!soft && constraint.isSoft) || constraint.weight > 0 {
 			continue
 		}
-		log.Debugf("matching constraint: %s%s%s (soft=%t)", constraint.key, OPERATORS[constraint.operator], constraint.value, constraint.isSoft)
+		log.Debugf("matching constraint: %s (soft=%t)", constraint.String(), constraint.isSoft)
 
 		candidates := []*node.Node{}
 		for _, node := range nodes {
-			switch constraint.key {
-			case "node":
-				// "node" label is a special case pinning a container to a specific node.
-				if constraint.Match(node.ID, node.Name) {
-					candidates = append(candidates, node)
-				}
-			default:
-				if constraint.Match(node.Labels[constraint.key]) {
-					candidates = append(candidates, node)
-				}
+			if constraint.MatchAny(func(constraint *expr) bool { return matchConstraint(constraint, node) }) {
+				candidates = append(candidates, node)
 			}
 		}
 		if len(candidates) == 0 {
-			return nil, fmt.Errorf("unable to find a node that satisfies the constraint %s%s%s", constraint.key, OPERATORS[constraint.operator], constraint.value)
+			return nil, fmt.Errorf("unable to find a node that satisfies the constraint %s", constraint.String())
 		}
 		nodes = candidates
 	}
 	return nodes, nil
 }
 
+func matchConstraint(constraint *expr, node *node.Node) bool {
+	switch constraint.key {
+	case "node":
+		// "node" label is a special case pinning a container to a specific node.
+		return constraint.Match(node.ID, node.Name)
+	default:
+		return constraint.Match(node.Labels[constraint.key])
+	}
+}
+
 // GetFilters returns a list of the constraints found in the container config.
 func (f *ConstraintFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
 	allConstraints := []string{}
@@ -60,7 +63,7 @@ func (f *ConstraintFilter) GetFilters(config *cluster.ContainerConfig) ([]string
 		return nil, err
 	}
 	for _, constraint := range constraints {
-		allConstraints = append(allConstraints, fmt.Sprintf("%s%s%s", constraint.key, OPERATORS[constraint.operator], constraint.value))
+		allConstraints = append(allConstraints, constraint.String())
 	}
 	return allConstraints, nil
 }
@@ -278,3 +278,120 @@ func TestFilterSoftConstraint(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, result, 0)
 }
+
+func TestConstraintComparison(t *testing.T) {
+	var (
+		f      = ConstraintFilter{}
+		nodes  = testFixtures()
+		result []*node.Node
+		err    error
+	)
+	nodes[0].Labels["cores"] = "8"
+	nodes[1].Labels["cores"] = "16"
+	nodes[2].Labels["cores"] = "32"
+	nodes[0].Labels["kernelversion"] = "3.19.0-59-generic"
+	nodes[1].Labels["kernelversion"] = "4.4.0-21-generic"
+	nodes[2].Labels["kernelversion"] = "4.10.0"
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:cores>=16"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, result[0], nodes[1])
+	assert.Equal(t, result[1], nodes[2])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:cores<16"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[0])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:kernelversion>=4.4"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, result[0], nodes[1])
+	assert.Equal(t, result[1], nodes[2])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:cores>64"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.Error(t, err)
+	assert.Len(t, result, 0)
+
+	// Soft comparisons are ignored when no node satisfies them
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:cores>~64"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, false)
+	assert.NoError(t, err)
+	assert.Len(t, result, 4)
+
+	filters, err := f.GetFilters(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:cores>=16"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}))
+	assert.NoError(t, err)
+	assert.Equal(t, filters, []string{"cores>=16"})
+}
+
+func TestConstraintSetAndOr(t *testing.T) {
+	var (
+		f      = ConstraintFilter{}
+		nodes  = testFixtures()
+		result []*node.Node
+		err    error
+	)
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:region in (us-east, eu)"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, result[0], nodes[1])
+	assert.Equal(t, result[1], nodes[2])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:region notin (us*)"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, result[0], nodes[2])
+	assert.Equal(t, result[1], nodes[3])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:node in (node-0-name, node-3-id)"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, result[0], nodes[0])
+	assert.Equal(t, result[1], nodes[3])
+
+	// Alternatives are ORed, constraints are still ANDed
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:region==eu || name==node0", "constraint:group==1"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[0])
+
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:region==asia || group==3"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "region==asia || group==3")
+	assert.Len(t, result, 0)
+
+	// A disjunction with a soft alternative is soft
+	result, err = f.Filter(cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:region==asia || group==~3"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{}), nodes, false)
+	assert.NoError(t, err)
+	assert.Len(t, result, 4)
+}
+
+func TestConstraintPreferences(t *testing.T) {
+	var (
+		f      = ConstraintFilter{}
+		nodes  = testFixtures()
+		result []*node.Node
+		err    error
+	)
+
+	// Preferences never filter nodes out.
+	config := cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:region==~us*@weight=2", "constraint:group==~1@weight=1", "constraint:region==~asia@weight=5"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 4)
+
+	scores := PreferenceScores(config, nodes)
+	assert.Equal(t, scores["node-0-id"], int64(3))
+	assert.Equal(t, scores["node-1-id"], int64(3))
+	assert.Equal(t, scores["node-2-id"], int64(0))
+	assert.Equal(t, scores["node-3-id"], int64(0))
+
+	// Soft expressions without a weight count as preferences of weight 1,
+	// hard ones don't count.
+	config = cluster.BuildContainerConfig(containertypes.Config{Env: []string{"constraint:group==~1", "constraint:region==~us*@weight=2", "constraint:region==us*"}}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	scores = PreferenceScores(config, nodes)
+	assert.Equal(t, scores["node-0-id"], int64(3))
+	assert.Equal(t, scores["node-1-id"], int64(3))
+	assert.Equal(t, scores["node-2-id"], int64(0))
+}
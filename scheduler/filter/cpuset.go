@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// CpusetFilter prevents containers pinned to CPUs (--cpuset-cpus) from
+// sharing cores with the containers already pinned on a node.
+type CpusetFilter struct {
+}
+
+// Name returns the name of the filter
+func (f *CpusetFilter) Name() string {
+	return "cpuset"
+}
+
+// Filter is exported
+func (f *CpusetFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, _ bool) ([]*node.Node, error) {
+	cpuset, err := cluster.ParseCpuset(config.HostConfig.CpusetCpus)
+	if err != nil {
+		return nil, err
+	}
+	if len(cpuset) == 0 {
+		return nodes, nil
+	}
+
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if !pinsAny(node, cpuset) {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("unable to find a node where CPUs %s are not pinned by another container", config.HostConfig.CpusetCpus)
+	}
+	return result, nil
+}
+
+// GetFilters returns the requested cpuset.
+func (f *CpusetFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	if config.HostConfig.CpusetCpus == "" {
+		return nil, nil
+	}
+	return []string{"cpuset=" + config.HostConfig.CpusetCpus}, nil
+}
+
+// pinsAny returns true if a container of the node is pinned to one of the
+// CPUs of cpuset.
+func pinsAny(n *node.Node, cpuset map[int]struct{}) bool {
+	for _, c := range n.Containers {
+		if c.Config == nil {
+			continue
+		}
+		pinned, err := cluster.ParseCpuset(c.Config.HostConfig.CpusetCpus)
+		if err != nil {
+			continue
+		}
+		for cpu := range pinned {
+			if _, ok := cpuset[cpu]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
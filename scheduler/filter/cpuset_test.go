@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func cpusetConfig(cpuset string) *cluster.ContainerConfig {
+	return cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{Resources: containertypes.Resources{CpusetCpus: cpuset}}, networktypes.NetworkingConfig{})
+}
+
+func TestCpusetFilter(t *testing.T) {
+	var (
+		f     = CpusetFilter{}
+		nodes = []*node.Node{
+			{
+				ID:   "node-0-id",
+				Name: "node-0-name",
+				Containers: []*cluster.Container{
+					{Container: types.Container{ID: "c0"}, Config: cpusetConfig("0-1")},
+				},
+			},
+			{
+				ID:   "node-1-id",
+				Name: "node-1-name",
+				Containers: []*cluster.Container{
+					{Container: types.Container{ID: "c1"}, Config: cpusetConfig("2")},
+					{Container: types.Container{ID: "c2"}, Config: cpusetConfig("")},
+				},
+			},
+			{
+				ID:   "node-2-id",
+				Name: "node-2-name",
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	// Containers which are not pinned can go anywhere.
+	result, err = f.Filter(cpusetConfig(""), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	result, err = f.Filter(cpusetConfig("1"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[1:])
+
+	result, err = f.Filter(cpusetConfig("0,2"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[2:])
+
+	result, err = f.Filter(cpusetConfig("3-5"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	result, err = f.Filter(cpusetConfig("1-2"), nodes[:2], true)
+	assert.Error(t, err)
+	assert.Len(t, result, 0)
+
+	_, err = f.Filter(cpusetConfig("1-"), nodes, true)
+	assert.Error(t, err)
+}
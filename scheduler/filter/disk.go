@@ -0,0 +1,158 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// defaultDiskLowWatermark is the percentage of free space under which a node
+// doesn't get containers.
+const defaultDiskLowWatermark = 5
+
+// DiskFilter keeps containers away from the nodes running out of space in
+// their storage driver. Nodes whose storage driver doesn't report its space
+// are not filtered.
+type DiskFilter struct {
+	// lowWatermark is the free space under which a node is excluded, in
+	// bytes, or as a percentage of its total space if percent is set.
+	lowWatermark int64
+	percent      bool
+	// pullSize counts the size of the image on the nodes that would need to
+	// pull it.
+	pullSize bool
+}
+
+// newDiskFilter creates a disk filter with options in the form key=value
+// (ex. lowwatermark=10GB or pullsize=true).
+func newDiskFilter(options []string) (*DiskFilter, error) {
+	f := &DiskFilter{lowWatermark: defaultDiskLowWatermark, percent: true}
+	for _, option := range options {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid option %q for the disk filter", option)
+		}
+		switch kv[0] {
+		case "lowwatermark":
+			if strings.HasSuffix(kv[1], "%") {
+				percent, err := strconv.ParseInt(strings.TrimSuffix(kv[1], "%"), 10, 64)
+				if err != nil || percent < 0 || percent > 100 {
+					return nil, fmt.Errorf("invalid low watermark %q for the disk filter", kv[1])
+				}
+				f.lowWatermark, f.percent = percent, true
+			} else {
+				size, err := units.FromHumanSize(kv[1])
+				if err != nil || size < 0 {
+					return nil, fmt.Errorf("invalid low watermark %q for the disk filter", kv[1])
+				}
+				f.lowWatermark, f.percent = size, false
+			}
+		case "pullsize":
+			pullSize, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid pull size %q for the disk filter", kv[1])
+			}
+			f.pullSize = pullSize
+		default:
+			return nil, fmt.Errorf("unknown option %q for the disk filter", kv[0])
+		}
+	}
+	return f, nil
+}
+
+// Name returns the name of the filter
+func (f *DiskFilter) Name() string {
+	return "disk"
+}
+
+// Filter is exported
+func (f *DiskFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, _ bool) ([]*node.Node, error) {
+	var imageSize int64
+	if f.pullSize {
+		imageSize = findImageSize(config.Image, nodes)
+	}
+
+	candidates := []*node.Node{}
+	for _, n := range nodes {
+		needed := int64(0)
+		if imageSize > 0 && !hasImage(n, config.Image) {
+			needed = imageSize
+		}
+		if f.hasSpace(n, needed) {
+			candidates = append(candidates, n)
+		}
+	}
+
+	if len(candidates) == 0 && len(nodes) > 0 {
+		list, _ := f.GetFilters(config)
+		return nil, fmt.Errorf("unable to find a node with enough disk space: %v", list)
+	}
+	return candidates, nil
+}
+
+// GetFilters returns the low watermark of the free disk space.
+func (f *DiskFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	condition := "free disk space above " + f.watermark()
+	if f.pullSize {
+		condition += " after pulling " + config.Image
+	}
+	return []string{condition}, nil
+}
+
+// NodeWarning warns about the nodes below the low watermark.
+func (f *DiskFilter) NodeWarning(n *node.Node) string {
+	if f.hasSpace(n, 0) {
+		return ""
+	}
+	return fmt.Sprintf("the free disk space is below the low watermark of %s (data: %s / %s, metadata: %s / %s)", f.watermark(),
+		units.HumanSize(float64(n.Disk.DataAvailable)), units.HumanSize(float64(n.Disk.DataTotal)),
+		units.HumanSize(float64(n.Disk.MetadataAvailable)), units.HumanSize(float64(n.Disk.MetadataTotal)))
+}
+
+// hasSpace returns true if the node is above the low watermark once needed
+// bytes are used. The metadata space is only checked against a percentage.
+func (f *DiskFilter) hasSpace(n *node.Node, needed int64) bool {
+	disk := n.Disk
+	if disk.DataTotal == 0 {
+		return true
+	}
+	if !f.percent {
+		return disk.DataAvailable-needed >= f.lowWatermark
+	}
+	if (disk.DataAvailable-needed)*100 < disk.DataTotal*f.lowWatermark {
+		return false
+	}
+	return disk.MetadataTotal == 0 || disk.MetadataAvailable*100 >= disk.MetadataTotal*f.lowWatermark
+}
+
+func (f *DiskFilter) watermark() string {
+	if f.percent {
+		return fmt.Sprintf("%d%%", f.lowWatermark)
+	}
+	return units.HumanSize(float64(f.lowWatermark))
+}
+
+// findImageSize returns the size of the image on the nodes having it, or 0.
+func findImageSize(name string, nodes []*node.Node) int64 {
+	for _, n := range nodes {
+		for _, image := range n.Images {
+			if image.Match(name, true) {
+				return image.Size
+			}
+		}
+	}
+	return 0
+}
+
+func hasImage(n *node.Node, name string) bool {
+	for _, image := range n.Images {
+		if image.Match(name, true) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func createDiskNodes() []*node.Node {
+	return []*node.Node{
+		{
+			ID:   "node-0-id",
+			Name: "node-0-name",
+			Disk: cluster.DiskStatus{DataAvailable: 3 << 30, DataTotal: 100 << 30, MetadataAvailable: 2 << 30, MetadataTotal: 2 << 30},
+		},
+		{
+			ID:   "node-1-id",
+			Name: "node-1-name",
+			Disk: cluster.DiskStatus{DataAvailable: 20 << 30, DataTotal: 100 << 30, MetadataAvailable: 2 << 30, MetadataTotal: 2 << 30},
+			Images: []*cluster.Image{
+				{Image: types.Image{ID: "sha256:big", RepoTags: []string{"big:latest"}, Size: 15 << 30}},
+			},
+		},
+		{
+			ID:   "node-2-id",
+			Name: "node-2-name",
+			Disk: cluster.DiskStatus{DataAvailable: 30 << 30, DataTotal: 100 << 30, MetadataAvailable: 1 << 20, MetadataTotal: 2 << 30},
+		},
+		{
+			// The storage driver doesn't report its space.
+			ID:   "node-3-id",
+			Name: "node-3-name",
+		},
+	}
+}
+
+func TestDiskFilter(t *testing.T) {
+	var (
+		nodes  = createDiskNodes()
+		config = &cluster.ContainerConfig{Config: containertypes.Config{Image: "big:latest"}}
+	)
+
+	// The default low watermark is 5% of the data and metadata space.
+	filters, err := New([]string{"disk"})
+	assert.NoError(t, err)
+	result, err := filters[0].Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []*node.Node{nodes[1], nodes[3]})
+	assert.Contains(t, filters[0].(NodeWarner).NodeWarning(nodes[0]), "below the low watermark of 5%")
+	assert.Empty(t, filters[0].(NodeWarner).NodeWarning(nodes[1]))
+
+	// Absolute low watermarks only check the data space.
+	filters, err = New([]string{"disk,lowwatermark=10GB"})
+	assert.NoError(t, err)
+	result, err = filters[0].Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []*node.Node{nodes[1], nodes[2], nodes[3]})
+
+	// The image must fit on the nodes that would pull it.
+	filters, err = New([]string{"disk,lowwatermark=10%,pullsize=true"})
+	assert.NoError(t, err)
+	result, err = filters[0].Filter(config, nodes[:3], true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []*node.Node{nodes[1]})
+
+	filters, err = New([]string{"disk,lowwatermark=25%"})
+	assert.NoError(t, err)
+	_, err = filters[0].Filter(config, nodes[:3], true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "25%")
+
+	for _, name := range []string{"disk,lowwatermark=101%", "disk,lowwatermark=lots", "disk,pullsize=maybe", "disk,size=1", "disk,pullsize", "health,foo=bar"} {
+		_, err = New([]string{name})
+		assert.Error(t, err, name)
+	}
+}
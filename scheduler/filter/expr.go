@@ -3,6 +3,7 @@ package filter
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -13,66 +14,166 @@ const (
 	EQ = iota
 	// NOTEQ is exported
 	NOTEQ
+	// GTE is exported
+	GTE
+	// LTE is exported
+	LTE
+	// GT is exported
+	GT
+	// LT is exported
+	LT
+	// IN is exported
+	IN
+	// NOTIN is exported
+	NOTIN
 )
 
 // OPERATORS is exported
-var OPERATORS = []string{"==", "!="}
+// Two-character operators must come first, so that ">=" isn't mistaken for ">".
+var OPERATORS = []string{"==", "!=", ">=", "<=", ">", "<", " in ", " notin "}
+
+// OR separates the alternatives of a disjunction (ex: zone==a || storage==ssd).
+const OR = "||"
+
+var (
+	versionRegexp = regexp.MustCompile(`^v?([0-9]+(\.[0-9]+)*)`)
+	// weightRegexp matches the weight turning a soft expression into a
+	// preference (ex: storage==~ssd@weight=5).
+	weightRegexp = regexp.MustCompile(`@weight=([0-9]+)$`)
+)
 
 type expr struct {
 	key      string
 	operator int
 	value    string
 	isSoft   bool
+	// values holds the members of the set for the in/notin operators.
+	values []string
+	// or holds the expressions ORed with this one.
+	or []expr
+	// weight is set on preferences: soft expressions which add to the score
+	// of the nodes satisfying them rather than filtering nodes out.
+	weight int64
 }
 
 func parseExprs(env []string) ([]expr, error) {
 	exprs := []expr{}
 	for _, e := range env {
-		found := false
-		for i, op := range OPERATORS {
-			if strings.Contains(e, op) {
-				// split with the op
-				parts := strings.SplitN(e, op, 2)
-
-				// validate key
-				// allow alpha-numeric
-				matched, err := regexp.MatchString(`^(?i)[a-z_][a-z0-9\-_.]+$`, parts[0])
-				if err != nil {
-					return nil, err
-				}
-				if matched == false {
-					return nil, fmt.Errorf("Key '%s' is invalid", parts[0])
-				}
-
-				if len(parts) == 2 {
-
-					// validate value
-					// allow leading = in case of using ==
-					// allow * for globbing
-					// allow regexp
-					matched, err := regexp.MatchString(`^(?i)[=!\/]?(~)?[a-z0-9:\-_\s\.\*/\(\)\?\+\[\]\\\^\$\|]+$`, parts[1])
-					if err != nil {
-						return nil, err
-					}
-					if matched == false {
-						return nil, fmt.Errorf("Value '%s' is invalid", parts[1])
-					}
-					exprs = append(exprs, expr{key: parts[0], operator: i, value: strings.TrimLeft(parts[1], "~"), isSoft: isSoft(parts[1])})
-				} else {
-					exprs = append(exprs, expr{key: parts[0], operator: i})
-				}
-
-				found = true
-				break // found an op, move to next entry
+		var weight int64
+		if matches := weightRegexp.FindStringSubmatchIndex(e); matches != nil {
+			w, err := strconv.ParseInt(e[matches[2]:matches[3]], 10, 64)
+			if err != nil || w == 0 {
+				return nil, fmt.Errorf("Weight '%s' is invalid", e[matches[2]:matches[3]])
 			}
+			weight = w
+			e = e[:matches[0]]
 		}
-		if !found {
-			return nil, fmt.Errorf("One of operator ==, != is expected")
+
+		var alternatives []expr
+		for _, term := range strings.Split(e, OR) {
+			alternative, err := parseExpr(strings.TrimSpace(term))
+			if err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, alternative)
+		}
+
+		// A disjunction is soft as soon as one of its alternatives is.
+		expr := alternatives[0]
+		for _, alternative := range alternatives[1:] {
+			expr.isSoft = expr.isSoft || alternative.isSoft
+			expr.or = append(expr.or, alternative)
+		}
+
+		if weight > 0 && !expr.isSoft {
+			return nil, fmt.Errorf("Expression '%s' must be soft to have a weight", expr.String())
 		}
+		expr.weight = weight
+		exprs = append(exprs, expr)
 	}
 	return exprs, nil
 }
 
+func parseExpr(e string) (expr, error) {
+	for i, op := range OPERATORS {
+		if !strings.Contains(e, op) {
+			continue
+		}
+		// split with the op
+		parts := strings.SplitN(e, op, 2)
+
+		// validate key
+		// allow alpha-numeric
+		matched, err := regexp.MatchString(`^(?i)[a-z_][a-z0-9\-_.]+$`, parts[0])
+		if err != nil {
+			return expr{}, err
+		}
+		if matched == false {
+			return expr{}, fmt.Errorf("Key '%s' is invalid", parts[0])
+		}
+
+		// validate value
+		// allow leading = in case of using ==
+		// allow * for globbing
+		// allow regexp
+		// only allow numbers and versions for comparisons
+		// only allow a list of globbing patterns for sets
+		pattern := `^(?i)[=!\/]?(~)?[a-z0-9:\-_\s\.\*/\(\)\?\+\[\]\\\^\$\|]+$`
+		if i == IN || i == NOTIN {
+			pattern = `^(?i)(~)?\(\s*[a-z0-9:\-_\.\*/]+(\s*,\s*[a-z0-9:\-_\.\*/]+)*\s*\)$`
+		} else if i >= GTE {
+			pattern = `^(?i)(~)?(-?[0-9]+(\.[0-9]+)?|v?[0-9]+(\.[0-9]+)*([\-+][a-z0-9\-_.+]*)?)$`
+		}
+		matched, err = regexp.MatchString(pattern, parts[1])
+		if err != nil {
+			return expr{}, err
+		}
+		if matched == false {
+			return expr{}, fmt.Errorf("Value '%s' is invalid", parts[1])
+		}
+
+		ex := expr{key: parts[0], operator: i, value: strings.TrimLeft(parts[1], "~"), isSoft: isSoft(parts[1])}
+		if i == IN || i == NOTIN {
+			for _, value := range strings.Split(strings.Trim(ex.value, "()"), ",") {
+				ex.values = append(ex.values, strings.TrimSpace(value))
+			}
+		}
+		return ex, nil
+	}
+
+	operators := []string{}
+	for _, op := range OPERATORS {
+		operators = append(operators, strings.TrimSpace(op))
+	}
+	return expr{}, fmt.Errorf("One of operator %s is expected", strings.Join(operators, ", "))
+}
+
+// String returns the expression as written by the user.
+func (e *expr) String() string {
+	s := e.key + OPERATORS[e.operator] + e.value
+	for _, alternative := range e.or {
+		s += " " + OR + " " + alternative.String()
+	}
+	if e.weight > 0 {
+		s += fmt.Sprintf("@weight=%d", e.weight)
+	}
+	return s
+}
+
+// MatchAny returns true if match returns true for the expression or one of
+// its alternatives.
+func (e *expr) MatchAny(match func(*expr) bool) bool {
+	if match(e) {
+		return true
+	}
+	for i := range e.or {
+		if match(&e.or[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *expr) Match(whats ...string) bool {
 	var (
 		pattern string
@@ -80,6 +181,25 @@ func (e *expr) Match(whats ...string) bool {
 		err     error
 	)
 
+	if e.operator == IN || e.operator == NOTIN {
+		for _, value := range e.values {
+			member := expr{operator: EQ, value: value}
+			if member.Match(whats...) {
+				return e.operator == IN
+			}
+		}
+		return e.operator == NOTIN
+	}
+
+	if e.operator >= GTE {
+		for _, what := range whats {
+			if cmp, ok := compare(what, e.value); ok && e.satisfies(cmp) {
+				return true
+			}
+		}
+		return false
+	}
+
 	if e.value[0] == '/' && e.value[len(e.value)-1] == '/' {
 		// regexp
 		pattern = e.value[1 : len(e.value)-1]
@@ -106,6 +226,84 @@ func (e *expr) Match(whats ...string) bool {
 	return false
 }
 
+// satisfies returns true if the result of a comparison satisfies the operator.
+func (e *expr) satisfies(cmp int) bool {
+	switch e.operator {
+	case GTE:
+		return cmp >= 0
+	case LTE:
+		return cmp <= 0
+	case GT:
+		return cmp > 0
+	case LT:
+		return cmp < 0
+	}
+	return false
+}
+
+// compare compares two values numerically if both are numbers (ex: 0.8 >
+// 0.75), or as versions otherwise (ex: 4.4.0-21-generic > 4.2). Anything
+// following the numeric part of a version is ignored. Returns false if the
+// values can't be compared.
+func compare(a, b string) (int, bool) {
+	if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		if fb, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return compareVersions(a, b)
+}
+
+func compareVersions(a, b string) (int, bool) {
+	va, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	vb, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var ca, cb int64
+		if i < len(va) {
+			ca = va[i]
+		}
+		if i < len(vb) {
+			cb = vb[i]
+		}
+		if ca != cb {
+			if ca < cb {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+func parseVersion(value string) ([]int64, bool) {
+	matches := versionRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, false
+	}
+
+	components := []int64{}
+	for _, component := range strings.Split(matches[1], ".") {
+		c, err := strconv.ParseInt(component, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		components = append(components, c)
+	}
+	return components, true
+}
+
 func isSoft(value string) bool {
 	if value[0] == '~' {
 		return true
@@ -82,3 +82,165 @@ func TestMatch(t *testing.T) {
 	assert.False(t, e.Match("fuo"))
 	assert.False(t, e.Match("foo", "fuo", "bar"))
 }
+
+func TestParseComparisonExprs(t *testing.T) {
+	exprs, err := parseExprs([]string{"cores>=16", "disk_gb>500", "kernelversion<=~4.4", "engineversion<1.12.0-rc1"})
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 4)
+	assert.Equal(t, exprs[0].key, "cores")
+	assert.Equal(t, exprs[0].operator, GTE)
+	assert.Equal(t, exprs[0].value, "16")
+	assert.Equal(t, exprs[1].key, "disk_gb")
+	assert.Equal(t, exprs[1].operator, GT)
+	assert.Equal(t, exprs[1].value, "500")
+	assert.Equal(t, exprs[2].key, "kernelversion")
+	assert.Equal(t, exprs[2].operator, LTE)
+	assert.Equal(t, exprs[2].value, "4.4")
+	assert.True(t, exprs[2].isSoft)
+	assert.Equal(t, exprs[3].operator, LT)
+	assert.Equal(t, exprs[3].value, "1.12.0-rc1")
+
+	// Only numbers and versions can be compared
+	_, err = parseExprs([]string{"cores>=many"})
+	assert.Error(t, err)
+	_, err = parseExprs([]string{"cores>=1*"})
+	assert.Error(t, err)
+
+	_, err = parseExprs([]string{"cores=>16"})
+	assert.Error(t, err)
+}
+
+func TestMatchComparison(t *testing.T) {
+	e := expr{operator: GTE, value: "16"}
+	assert.True(t, e.Match("16"))
+	assert.True(t, e.Match("32"))
+	assert.False(t, e.Match("8"))
+	assert.False(t, e.Match(""))
+	assert.False(t, e.Match("many"))
+	assert.True(t, e.Match("8", "32"))
+
+	e = expr{operator: GT, value: "500"}
+	assert.True(t, e.Match("500.5"))
+	assert.False(t, e.Match("500"))
+
+	e = expr{operator: LT, value: "-1"}
+	assert.True(t, e.Match("-2"))
+	assert.False(t, e.Match("0"))
+
+	e = expr{operator: LTE, value: "0.5"}
+	assert.True(t, e.Match("0.4"))
+	assert.False(t, e.Match("1"))
+
+	// Versions are compared component by component
+	e = expr{operator: GTE, value: "4.4"}
+	assert.True(t, e.Match("4.4.0-21-generic"))
+	assert.True(t, e.Match("4.10.0"))
+	assert.False(t, e.Match("3.19.0-59-generic"))
+
+	e = expr{operator: LT, value: "1.12.0"}
+	assert.True(t, e.Match("1.11.2"))
+	assert.True(t, e.Match("v1.9"))
+	assert.False(t, e.Match("1.12.0"))
+	assert.False(t, e.Match("1.12.1-rc1"))
+}
+
+func TestCompare(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		cmp  int
+		ok   bool
+	}{
+		// Numbers, decimal or negative, are compared numerically.
+		{"0.8", "0.75", 1, true},
+		{"1.25", "1.5", -1, true},
+		{"-1.5", "-1", -1, true},
+		{"-1.5", "-1.50", 0, true},
+		{"2", "10", -1, true},
+		// Versions are compared component by component.
+		{"4.10.0", "4.9.1", 1, true},
+		{"4.4.0-21-generic", "4.4", 0, true},
+		{"v1.9", "1.12.0", -1, true},
+		{"4.10", "4.9-rc1", 1, true},
+		// Anything else can't be compared.
+		{"many", "1", 0, false},
+		{"-1.5", "1.2.3", 0, false},
+	} {
+		cmp, ok := compare(test.a, test.b)
+		assert.Equal(t, test.ok, ok, "%s <=> %s", test.a, test.b)
+		assert.Equal(t, test.cmp, cmp, "%s <=> %s", test.a, test.b)
+	}
+}
+
+func TestParseSetExprs(t *testing.T) {
+	exprs, err := parseExprs([]string{"zone in (us-east-1a, us-east-1b)", "zone notin ~(eu*)"})
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 2)
+	assert.Equal(t, exprs[0].key, "zone")
+	assert.Equal(t, exprs[0].operator, IN)
+	assert.Equal(t, exprs[0].values, []string{"us-east-1a", "us-east-1b"})
+	assert.False(t, exprs[0].isSoft)
+	assert.Equal(t, exprs[0].String(), "zone in (us-east-1a, us-east-1b)")
+	assert.Equal(t, exprs[1].operator, NOTIN)
+	assert.Equal(t, exprs[1].values, []string{"eu*"})
+	assert.True(t, exprs[1].isSoft)
+
+	// Sets must be in parentheses
+	_, err = parseExprs([]string{"zone in us-east-1a,us-east-1b"})
+	assert.Error(t, err)
+	_, err = parseExprs([]string{"zone in ()"})
+	assert.Error(t, err)
+	_, err = parseExprs([]string{"zone in (a,)"})
+	assert.Error(t, err)
+}
+
+func TestParseOrExprs(t *testing.T) {
+	exprs, err := parseExprs([]string{"zone==a || storage==~ssd || cores>=16"})
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 1)
+	assert.Equal(t, exprs[0].key, "zone")
+	assert.Len(t, exprs[0].or, 2)
+	assert.Equal(t, exprs[0].or[0].key, "storage")
+	assert.Equal(t, exprs[0].or[1].operator, GTE)
+	assert.True(t, exprs[0].isSoft)
+	assert.Equal(t, exprs[0].String(), "zone==a || storage==ssd || cores>=16")
+
+	// Every alternative must be valid
+	_, err = parseExprs([]string{"zone==a || storage"})
+	assert.Error(t, err)
+	_, err = parseExprs([]string{"zone==a ||"})
+	assert.Error(t, err)
+}
+
+func TestMatchSet(t *testing.T) {
+	e := expr{operator: IN, values: []string{"foo", "ba*"}}
+	assert.True(t, e.Match("foo"))
+	assert.True(t, e.Match("baz"))
+	assert.False(t, e.Match("qux"))
+	assert.True(t, e.Match("qux", "bar"))
+
+	e = expr{operator: NOTIN, values: []string{"foo", "ba*"}}
+	assert.False(t, e.Match("foo"))
+	assert.False(t, e.Match("baz"))
+	assert.True(t, e.Match("qux"))
+	assert.False(t, e.Match("qux", "bar"))
+}
+
+func TestParseWeightedExprs(t *testing.T) {
+	exprs, err := parseExprs([]string{"storage==~ssd@weight=5", "zone==~a || zone==~b@weight=2", "storage==~ssd"})
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 3)
+	assert.Equal(t, exprs[0].value, "ssd")
+	assert.Equal(t, exprs[0].weight, int64(5))
+	assert.Equal(t, exprs[0].String(), "storage==ssd@weight=5")
+	assert.Equal(t, exprs[1].or[0].value, "b")
+	assert.Equal(t, exprs[1].weight, int64(2))
+	assert.Equal(t, exprs[2].weight, int64(0))
+
+	// Only soft expressions can be weighted
+	_, err = parseExprs([]string{"storage==ssd@weight=5"})
+	assert.Error(t, err)
+	_, err = parseExprs([]string{"storage==~ssd@weight=0"})
+	assert.Error(t, err)
+	_, err = parseExprs([]string{"storage==~ssd@weight=-1"})
+	assert.Error(t, err)
+}
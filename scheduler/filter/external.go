@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+const (
+	// ExternalPrefix prefixes the filters implemented by a remote HTTP endpoint
+	// (ex: --filter external:licence=http://licence-server:8080/filter).
+	ExternalPrefix = "external:"
+
+	defaultExternalTimeout = 5 * time.Second
+)
+
+var externalNameRegexp = regexp.MustCompile(`^(?i)[a-z0-9][a-z0-9\-_.]*$`)
+
+// ExternalFilter delegates the filtering of nodes to a remote HTTP endpoint.
+type ExternalFilter struct {
+	name     string
+	url      string
+	failOpen bool
+	client   *http.Client
+}
+
+// ExternalFilterRequest is the body POSTed to the endpoint of an external filter.
+type ExternalFilterRequest struct {
+	Config *cluster.ContainerConfig
+	Nodes  []*node.Description
+	// Soft is false when soft constraints and affinities should be ignored.
+	Soft bool
+}
+
+// ExternalFilterResponse is the answer expected from the endpoint of an
+// external filter.
+type ExternalFilterResponse struct {
+	// Accepted holds the IDs of the nodes accepted by the filter.
+	Accepted []string
+	// Reason explains the decision, and is reported to the user when no node
+	// is accepted.
+	Reason string
+}
+
+// NewExternalFilter creates an external filter from its specification, in
+// the form <name>=<url>[,timeout=<duration>][,fail=open|closed]. By default,
+// requests time out after 5s and fail closed, rejecting every node.
+func NewExternalFilter(spec string) (*ExternalFilter, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || !externalNameRegexp.MatchString(parts[0]) {
+		return nil, fmt.Errorf("invalid external filter %q, expected %s<name>=<url>", spec, ExternalPrefix)
+	}
+	for _, filter := range filters {
+		if filter.Name() == parts[0] {
+			return nil, fmt.Errorf("invalid external filter %q, %s is a builtin filter", spec, parts[0])
+		}
+	}
+
+	f := &ExternalFilter{
+		name:   parts[0],
+		client: &http.Client{Timeout: defaultExternalTimeout},
+	}
+
+	options := strings.Split(parts[1], ",")
+	f.url = options[0]
+	if u, err := url.Parse(f.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid URL %q for external filter %s", f.url, f.name)
+	}
+
+	for _, option := range options[1:] {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid option %q for external filter %s", option, f.name)
+		}
+		switch kv[0] {
+		case "timeout":
+			timeout, err := time.ParseDuration(kv[1])
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid timeout %q for external filter %s", kv[1], f.name)
+			}
+			f.client.Timeout = timeout
+		case "fail":
+			if kv[1] != "open" && kv[1] != "closed" {
+				return nil, fmt.Errorf("invalid fail mode %q for external filter %s, expected open or closed", kv[1], f.name)
+			}
+			f.failOpen = kv[1] == "open"
+		default:
+			return nil, fmt.Errorf("unknown option %q for external filter %s", kv[0], f.name)
+		}
+	}
+	return f, nil
+}
+
+// Name returns the name of the filter
+func (f *ExternalFilter) Name() string {
+	return f.name
+}
+
+// Filter sends the candidate nodes to the endpoint, and keeps the ones it accepts.
+func (f *ExternalFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, soft bool) ([]*node.Node, error) {
+	result, _, err := f.Explain(config, nodes, soft)
+	return result, err
+}
+
+// Explain filters the nodes, and returns the reason given by the endpoint.
+func (f *ExternalFilter) Explain(config *cluster.ContainerConfig, nodes []*node.Node, soft bool) ([]*node.Node, string, error) {
+	response, err := f.call(config, nodes, soft)
+	if err != nil {
+		log.WithFields(log.Fields{"name": f.name, "url": f.url}).WithError(err).Error("External filter failed")
+		if f.failOpen {
+			return nodes, "", nil
+		}
+		err = fmt.Errorf("external filter %s failed: %v", f.name, err)
+		return nil, err.Error(), err
+	}
+
+	accepted := make(map[string]struct{}, len(response.Accepted))
+	for _, ID := range response.Accepted {
+		accepted[ID] = struct{}{}
+	}
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if _, ok := accepted[node.ID]; ok {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		if response.Reason != "" {
+			return nil, response.Reason, fmt.Errorf("unable to find a node accepted by the external filter %s: %s", f.name, response.Reason)
+		}
+		return nil, "", fmt.Errorf("unable to find a node accepted by the external filter %s", f.name)
+	}
+	return result, response.Reason, nil
+}
+
+// GetFilters returns nothing, the conditions are only known to the endpoint.
+// Explain gives the reason of each decision.
+func (f *ExternalFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	return nil, nil
+}
+
+func (f *ExternalFilter) call(config *cluster.ContainerConfig, nodes []*node.Node, soft bool) (*ExternalFilterResponse, error) {
+	request := ExternalFilterRequest{
+		Config: config,
+		Nodes:  make([]*node.Description, 0, len(nodes)),
+		Soft:   soft,
+	}
+	for _, n := range nodes {
+		request.Nodes = append(request.Nodes, n.Describe())
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Post(f.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	response := &ExternalFilterResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExternalFilter(t *testing.T) {
+	f, err := NewExternalFilter("licence=http://licence:8080/filter")
+	assert.NoError(t, err)
+	assert.Equal(t, f.Name(), "licence")
+	assert.Equal(t, f.url, "http://licence:8080/filter")
+	assert.Equal(t, f.client.Timeout, defaultExternalTimeout)
+	assert.False(t, f.failOpen)
+
+	f, err = NewExternalFilter("licence=https://licence/filter,timeout=500ms,fail=open")
+	assert.NoError(t, err)
+	assert.Equal(t, f.client.Timeout, 500*time.Millisecond)
+	assert.True(t, f.failOpen)
+
+	for _, spec := range []string{
+		"licence",
+		"=http://licence",
+		"licence=licence:8080",
+		"health=http://licence",
+		"licence=http://licence,timeout=forever",
+		"licence=http://licence,fail=maybe",
+		"licence=http://licence,retries=3",
+	} {
+		_, err = NewExternalFilter(spec)
+		assert.Error(t, err, spec)
+	}
+
+	filters, err := New([]string{"health", ExternalPrefix + "licence=http://licence"})
+	assert.NoError(t, err)
+	assert.Len(t, filters, 2)
+	assert.Equal(t, filters[1].Name(), "licence")
+}
+
+func TestExternalFilter(t *testing.T) {
+	var request ExternalFilterRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = ExternalFilterRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		response := ExternalFilterResponse{Reason: "licence available in us-east"}
+		for _, n := range request.Nodes {
+			if n.Labels["region"] == "us-east" {
+				response.Accepted = append(response.Accepted, n.ID)
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	f, err := NewExternalFilter("licence=" + server.URL)
+	assert.NoError(t, err)
+
+	nodes := testFixtures()
+	config := cluster.BuildContainerConfig(containertypes.Config{Image: "oracle"}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	result, err := f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[1])
+
+	assert.Equal(t, request.Config.Image, "oracle")
+	assert.True(t, request.Soft)
+	assert.Len(t, request.Nodes, 4)
+	assert.Equal(t, request.Nodes[0].ID, "node-0-id")
+
+	result, reason, err := f.Explain(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, reason, "licence available in us-east")
+
+	result, err = f.Filter(config, []*node.Node{nodes[0], nodes[2]}, true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "licence available in us-east")
+	assert.Len(t, result, 0)
+}
+
+func TestExternalFilterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	nodes := testFixtures()
+	config := cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+
+	// Fail closed
+	f, err := NewExternalFilter("licence=" + server.URL + ",timeout=50ms")
+	assert.NoError(t, err)
+	result, err := f.Filter(config, nodes, true)
+	assert.Error(t, err)
+	assert.Len(t, result, 0)
+
+	// Fail open
+	f, err = NewExternalFilter("licence=" + server.URL + ",timeout=50ms,fail=open")
+	assert.NoError(t, err)
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	// Errors are failures too
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer errServer.Close()
+	f, err = NewExternalFilter("licence=" + errServer.URL)
+	assert.NoError(t, err)
+	_, err = f.Filter(config, nodes, true)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package filter
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
@@ -20,8 +21,28 @@ type Filter interface {
 	GetFilters(*cluster.ContainerConfig) ([]string, error)
 }
 
+// NodeWarner is implemented by the filters excluding nodes regardless of the
+// container, to warn about these nodes in docker info.
+type NodeWarner interface {
+	// Return a warning if the node is excluded, or an empty string.
+	NodeWarning(*node.Node) string
+}
+
+// Explainer is implemented by the filters whose decisions can't be told from
+// their conditions, to give the reason of each of them.
+type Explainer interface {
+	// Return the nodes accepted like Filter, along with the reason of the
+	// decision.
+	Explain(*cluster.ContainerConfig, []*node.Node, bool) ([]*node.Node, string, error)
+}
+
 var (
 	filters []Filter
+	// optional are the filters which are only used when selected.
+	optional = map[string]bool{
+		"capability": true,
+		"disk":       true,
+	}
 	// ErrNotSupported is exported
 	ErrNotSupported = errors.New("filter not supported")
 )
@@ -31,9 +52,15 @@ func init() {
 		&HealthFilter{},
 		&PortFilter{},
 		&SlotsFilter{},
+		&ResourceFilter{},
+		&CpusetFilter{},
 		&DependencyFilter{},
+		&VolumeFilter{},
+		&CapabilityFilter{},
 		&AffinityFilter{},
 		&ConstraintFilter{},
+		&TaintFilter{},
+		&DiskFilter{lowWatermark: defaultDiskLowWatermark, percent: true},
 	}
 }
 
@@ -42,6 +69,29 @@ func New(names []string) ([]Filter, error) {
 	var selectedFilters []Filter
 
 	for _, name := range names {
+		if strings.HasPrefix(name, ExternalPrefix) {
+			filter, err := NewExternalFilter(strings.TrimPrefix(name, ExternalPrefix))
+			if err != nil {
+				return nil, err
+			}
+			log.WithField("name", filter.Name()).Debug("Initializing external filter")
+			selectedFilters = append(selectedFilters, filter)
+			continue
+		}
+
+		if options := strings.Split(name, ","); len(options) > 1 {
+			if options[0] != "disk" {
+				return nil, fmt.Errorf("the %s filter doesn't support options", options[0])
+			}
+			filter, err := newDiskFilter(options[1:])
+			if err != nil {
+				return nil, err
+			}
+			log.WithField("name", filter.Name()).Debug("Initializing filter")
+			selectedFilters = append(selectedFilters, filter)
+			continue
+		}
+
 		found := false
 		for _, filter := range filters {
 			if filter.Name() == name {
@@ -68,16 +118,26 @@ func ApplyFilters(filters []Filter, config *cluster.ContainerConfig, nodes []*no
 	for _, filter := range filters {
 		candidates, err = filter.Filter(config, candidates, soft)
 		if err != nil {
-			// special case for when no healthy nodes are found
-			if filter.Name() == "health" {
-				return nil, err
-			}
-			return nil, fmt.Errorf("Unable to find a node that satisfies the following conditions %s", listAllFilters(filters, config, filter.Name()))
+			return nil, RejectionError(filters, config, filter, err)
 		}
 	}
 	return candidates, nil
 }
 
+// RejectionError returns the error of the scheduling when the filter failed
+// with err.
+func RejectionError(filters []Filter, config *cluster.ContainerConfig, filter Filter, err error) error {
+	// special case for when no healthy nodes are found
+	if filter.Name() == "health" {
+		return err
+	}
+	// The errors of the explainers hold the reason of the decision.
+	if _, ok := filter.(Explainer); ok {
+		return err
+	}
+	return fmt.Errorf("Unable to find a node that satisfies the following conditions %s", listAllFilters(filters, config, filter.Name()))
+}
+
 // listAllFilters creates a string containing all applied filters
 func listAllFilters(filters []Filter, config *cluster.ContainerConfig, lastFilter string) string {
 	allFilters := ""
@@ -103,3 +163,16 @@ func List() []string {
 
 	return names
 }
+
+// Defaults returns the names of the filters used when none is selected
+func Defaults() []string {
+	names := []string{}
+
+	for _, filter := range filters {
+		if !optional[filter.Name()] {
+			names = append(names, filter.Name())
+		}
+	}
+
+	return names
+}
@@ -77,3 +77,15 @@ func TestApplyFilters(t *testing.T) {
 	assert.Len(t, result, 1)
 
 }
+
+func TestDefaults(t *testing.T) {
+	// Optional filters are available, but not used by default.
+	assert.Contains(t, List(), "capability")
+	assert.NotContains(t, Defaults(), "capability")
+	assert.NotContains(t, Defaults(), "disk")
+	assert.Contains(t, Defaults(), "health")
+
+	filters, err := New([]string{"capability"})
+	assert.NoError(t, err)
+	assert.Len(t, filters, 1)
+}
@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// PreferenceScores returns, for each node, the sum of the weights of the
+// preferences it satisfies. Preferences are the soft constraints and
+// affinities, weighted (ex: constraint:storage==~ssd@weight=5) or with a
+// weight of 1 by default, so that they still count once the soft expressions
+// are discarded by the filters. Each PreferNoSchedule
+// taint not tolerated by the container counts as a preference of weight -1.
+// Nodes with a score of 0 may not be part of the result.
+func PreferenceScores(config *cluster.ContainerConfig, nodes []*node.Node) map[string]int64 {
+	scores := make(map[string]int64)
+
+	// Invalid expressions are reported by the filters.
+	constraints, _ := parseExprs(config.Constraints())
+	affinities, _ := parseExprs(config.Affinities())
+	tolerations := config.Tolerations()
+
+	for _, n := range nodes {
+		if taints := untoleratedTaints(n, tolerations, cluster.TaintPreferNoSchedule); len(taints) > 0 {
+			scores[n.ID] -= int64(len(taints))
+		}
+		for _, constraint := range constraints {
+			if weight := constraint.preferenceWeight(); weight > 0 && constraint.MatchAny(func(constraint *expr) bool { return matchConstraint(constraint, n) }) {
+				scores[n.ID] += weight
+			}
+		}
+		for _, affinity := range affinities {
+			if weight := affinity.preferenceWeight(); weight > 0 && affinity.MatchAny(func(affinity *expr) bool { return matchAffinity(affinity, n) }) {
+				scores[n.ID] += weight
+			}
+		}
+	}
+	return scores
+}
+
+// preferenceWeight returns the weight of a soft expression, 1 if it has none.
+// Hard expressions are not preferences.
+func (e *expr) preferenceWeight() int64 {
+	if !e.isSoft {
+		return 0
+	}
+	if e.weight == 0 {
+		return 1
+	}
+	return e.weight
+}
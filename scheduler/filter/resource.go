@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// ResourceFilter only schedules containers on nodes with enough free units of
+// the countable resources they request (ex. GPUs or licences).
+type ResourceFilter struct {
+}
+
+// Name returns the name of the filter
+func (f *ResourceFilter) Name() string {
+	return "resource"
+}
+
+// Filter is exported
+func (f *ResourceFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, _ bool) ([]*node.Node, error) {
+	resources := config.Resources()
+	if len(resources) == 0 {
+		return nodes, nil
+	}
+
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if hasFreeResources(node, resources) {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		list, _ := f.GetFilters(config)
+		return nil, fmt.Errorf("unable to find a node with enough free resources: %v", list)
+	}
+	return result, nil
+}
+
+// GetFilters returns the requested resources, in the form name=amount.
+func (f *ResourceFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	resources := config.Resources()
+	list := make([]string, 0, len(resources))
+	for name, amount := range resources {
+		list = append(list, fmt.Sprintf("%s=%d", name, amount))
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+func hasFreeResources(n *node.Node, resources map[string]int64) bool {
+	for name, amount := range resources {
+		if n.TotalResources[name]-n.UsedResources[name] < amount {
+			return false
+		}
+	}
+	return true
+}
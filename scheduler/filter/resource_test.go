@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func resourceConfig(env ...string) *cluster.ContainerConfig {
+	return cluster.BuildContainerConfig(containertypes.Config{Env: env}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+}
+
+func TestResourceFilter(t *testing.T) {
+	var (
+		f     = ResourceFilter{}
+		nodes = []*node.Node{
+			{
+				ID:             "node-0-id",
+				Name:           "node-0-name",
+				TotalResources: map[string]int64{"gpu": 2},
+			},
+			{
+				ID:             "node-1-id",
+				Name:           "node-1-name",
+				TotalResources: map[string]int64{"gpu": 1, "license.matlab": 1},
+			},
+			{
+				ID:   "node-2-id",
+				Name: "node-2-name",
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	// Containers without requests can go anywhere.
+	result, err = f.Filter(resourceConfig(), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	result, err = f.Filter(resourceConfig("resource:gpu=1"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:2])
+
+	result, err = f.Filter(resourceConfig("resource:gpu=2"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	result, err = f.Filter(resourceConfig("resource:gpu=1", "resource:license.matlab=1"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[1:2])
+
+	// Used units are not available anymore.
+	config := resourceConfig("resource:gpu=1")
+	assert.NoError(t, nodes[0].AddContainer(&cluster.Container{Container: types.Container{ID: "c1"}, Config: config}))
+	assert.NoError(t, nodes[1].AddContainer(&cluster.Container{Container: types.Container{ID: "c2"}, Config: config}))
+	result, err = f.Filter(config, nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	result, err = f.Filter(resourceConfig("resource:gpu=2"), nodes, true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "gpu=2")
+	assert.Len(t, result, 0)
+
+	nodes[0].RemoveContainer(&cluster.Container{Container: types.Container{ID: "c1"}, Config: config})
+	result, err = f.Filter(resourceConfig("resource:gpu=2"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+}
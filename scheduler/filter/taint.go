@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// TaintFilter keeps containers away from nodes with a NoSchedule taint they
+// don't tolerate (ex. taint.gpu=true:NoSchedule).
+type TaintFilter struct {
+}
+
+// Name returns the name of the filter
+func (f *TaintFilter) Name() string {
+	return "taint"
+}
+
+// Filter is exported
+func (f *TaintFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, _ bool) ([]*node.Node, error) {
+	tolerations := config.Tolerations()
+
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if len(untoleratedTaints(node, tolerations, cluster.TaintNoSchedule)) == 0 {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 && len(nodes) > 0 {
+		list, _ := f.GetFilters(config)
+		return nil, fmt.Errorf("unable to find a node without a NoSchedule taint not tolerated by the container: %v", list)
+	}
+	return result, nil
+}
+
+// GetFilters returns the tolerations of the container.
+func (f *TaintFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	tolerations := config.Tolerations()
+	list := make([]string, 0, len(tolerations))
+	for _, toleration := range tolerations {
+		s := toleration.Key
+		if toleration.Value != "" {
+			s += "=" + toleration.Value
+		}
+		if toleration.Effect != "" {
+			s += ":" + toleration.Effect
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// untoleratedTaints returns the taints of the node with the given effect not
+// tolerated by the container.
+func untoleratedTaints(n *node.Node, tolerations []cluster.Toleration, effect string) []cluster.Taint {
+	taints := []cluster.Taint{}
+	for _, taint := range cluster.Taints(n.Labels) {
+		if taint.Effect == effect && !cluster.Tolerated(taint, tolerations) {
+			taints = append(taints, taint)
+		}
+	}
+	return taints
+}
@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaintFilter(t *testing.T) {
+	var (
+		f     = TaintFilter{}
+		nodes = []*node.Node{
+			{
+				ID:     "node-0-id",
+				Name:   "node-0-name",
+				Labels: map[string]string{"taint.gpu": "true:NoSchedule"},
+			},
+			{
+				ID:     "node-1-id",
+				Name:   "node-1-name",
+				Labels: map[string]string{"taint.edge": "true:PreferNoSchedule"},
+			},
+			{
+				ID:     "node-2-id",
+				Name:   "node-2-name",
+				Labels: map[string]string{},
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	// Ordinary containers stay away from the NoSchedule taint.
+	result, err = f.Filter(resourceConfig(), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[1:])
+
+	// Tolerating the taint gives access to the node.
+	result, err = f.Filter(resourceConfig("toleration:gpu=true:NoSchedule"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	result, err = f.Filter(resourceConfig("toleration:gpu=false"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[1:])
+
+	// No node left.
+	_, err = f.Filter(resourceConfig("toleration:gpu=false"), nodes[:1], true)
+	assert.Error(t, err)
+
+	list, err := f.GetFilters(resourceConfig("toleration:gpu", "toleration:edge=true:PreferNoSchedule"))
+	assert.NoError(t, err)
+	assert.Equal(t, list, []string{"gpu", "edge=true:PreferNoSchedule"})
+}
+
+func TestPreferNoScheduleTaint(t *testing.T) {
+	nodes := []*node.Node{
+		{ID: "node-0-id", Labels: map[string]string{"taint.edge": "true:PreferNoSchedule", "taint.slow": "true:PreferNoSchedule"}},
+		{ID: "node-1-id", Labels: map[string]string{"taint.gpu": "true:NoSchedule"}},
+	}
+
+	assert.Equal(t, PreferenceScores(resourceConfig(), nodes), map[string]int64{"node-0-id": -2})
+	assert.Equal(t, PreferenceScores(resourceConfig("toleration:edge"), nodes), map[string]int64{"node-0-id": -1})
+	assert.Empty(t, PreferenceScores(resourceConfig("toleration:edge", "toleration:slow"), nodes))
+}
@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// VolumeFilter schedules containers on the nodes holding their local named
+// volumes.
+type VolumeFilter struct {
+}
+
+// Name returns the name of the filter
+func (f *VolumeFilter) Name() string {
+	return "volume"
+}
+
+// Filter is exported
+func (f *VolumeFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node, _ bool) ([]*node.Node, error) {
+	if len(nodes) == 0 {
+		return nodes, nil
+	}
+
+	binds := []string{}
+	for _, bind := range config.HostConfig.Binds {
+		if _, _, ok := cluster.ParseVolumeBind(bind); ok {
+			binds = append(binds, bind)
+		}
+	}
+	if len(binds) == 0 {
+		return nodes, nil
+	}
+
+	// Volumes that are not local to a node, or that don't exist yet, don't
+	// constrain the placement.
+	pinned := []string{}
+	for _, bind := range binds {
+		prefix, name, _ := cluster.ParseVolumeBind(bind)
+		if prefix != "" || isLocalVolume(name, nodes) {
+			pinned = append(pinned, bind)
+		}
+	}
+
+	candidates := []*node.Node{}
+	for _, node := range nodes {
+		if f.check(pinned, node) {
+			candidates = append(candidates, node)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("unable to find a node with the volumes: %s", strings.Join(pinned, " "))
+	}
+
+	return candidates, nil
+}
+
+// GetFilters returns the named volumes found in the container config.
+func (f *VolumeFilter) GetFilters(config *cluster.ContainerConfig) ([]string, error) {
+	volumes := []string{}
+	for _, bind := range config.HostConfig.Binds {
+		if _, _, ok := cluster.ParseVolumeBind(bind); ok {
+			volumes = append(volumes, strings.SplitN(bind, ":", 2)[0])
+		}
+	}
+	return volumes, nil
+}
+
+// Ensure that the node holds all the pinned volumes.
+func (f *VolumeFilter) check(binds []string, n *node.Node) bool {
+	for _, bind := range binds {
+		prefix, name, _ := cluster.ParseVolumeBind(bind)
+		if prefix != "" {
+			if prefix != n.Name && prefix != n.ID {
+				return false
+			}
+			continue
+		}
+		if volume := nodeVolume(n, name); volume == nil || !volume.IsLocal() {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocalVolume returns true if the volume only exists locally on the nodes
+// holding it.
+func isLocalVolume(name string, nodes []*node.Node) bool {
+	found := false
+	for _, n := range nodes {
+		if volume := nodeVolume(n, name); volume != nil {
+			if !volume.IsLocal() {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+func nodeVolume(n *node.Node, name string) *cluster.Volume {
+	for _, volume := range n.Volumes {
+		if volume.Name == name {
+			return volume
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func volumeConfig(binds ...string) *cluster.ContainerConfig {
+	return &cluster.ContainerConfig{HostConfig: containertypes.HostConfig{Binds: binds}}
+}
+
+func TestVolumeFilter(t *testing.T) {
+	var (
+		f     = VolumeFilter{}
+		nodes = []*node.Node{
+			{
+				ID:   "node-0-id",
+				Name: "node-0-name",
+				Volumes: cluster.Volumes{
+					{Volume: types.Volume{Name: "data", Driver: "local"}},
+					{Volume: types.Volume{Name: "shared", Driver: "rexray", Scope: "global"}},
+				},
+			},
+			{
+				ID:   "node-1-id",
+				Name: "node-1-name",
+				Volumes: cluster.Volumes{
+					{Volume: types.Volume{Name: "logs", Driver: "local", Scope: "local"}},
+					{Volume: types.Volume{Name: "shared", Driver: "rexray", Scope: "global"}},
+				},
+			},
+			{
+				ID:   "node-2-id",
+				Name: "node-2-name",
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	// Host paths, anonymous, shared and new volumes don't filter anything.
+	result, err = f.Filter(volumeConfig("/tmp:/tmp", "shared:/shared", "new:/new"), nodes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	// Local volumes pin the container to their node.
+	result, err = f.Filter(volumeConfig("data:/data"), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[0])
+
+	result, err = f.Filter(volumeConfig("logs:/logs:ro", "shared:/shared"), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[1])
+
+	// Volumes prefixed by a node name or ID pin the container to the node.
+	result, err = f.Filter(volumeConfig("node-2-name/new:/new"), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[2])
+
+	result, err = f.Filter(volumeConfig("node-1-id/logs:/logs"), nodes, true)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[1])
+
+	// Local volumes on different nodes can't be satisfied.
+	_, err = f.Filter(volumeConfig("data:/data", "logs:/logs"), nodes, true)
+	assert.Error(t, err)
+
+	_, err = f.Filter(volumeConfig("node-1-name/data:/data", "data:/data"), nodes, true)
+	assert.Error(t, err)
+}
+
+func TestVolumeFilterGetFilters(t *testing.T) {
+	f := VolumeFilter{}
+	volumes, err := f.GetFilters(volumeConfig("/tmp:/tmp", "data:/data", "node-1/logs:/logs:ro"))
+	assert.NoError(t, err)
+	assert.Equal(t, volumes, []string{"data", "node-1/logs"})
+}
@@ -0,0 +1,40 @@
+package node
+
+import "github.com/docker/engine-api/types"
+
+// Description is the serializable view of a node sent to the external
+// filters and strategies.
+type Description struct {
+	ID              string
+	Name            string
+	IP              string
+	Addr            string
+	Labels          map[string]string
+	Containers      []types.Container
+	UsedMemory      int64
+	UsedCpus        float64
+	TotalMemory     int64
+	TotalCpus       float64
+	HealthIndicator int64
+}
+
+// Describe returns the description of the node.
+func (n *Node) Describe() *Description {
+	containers := make([]types.Container, 0, len(n.Containers))
+	for _, c := range n.Containers {
+		containers = append(containers, c.Container)
+	}
+	return &Description{
+		ID:              n.ID,
+		Name:            n.Name,
+		IP:              n.IP,
+		Addr:            n.Addr,
+		Labels:          n.Labels,
+		Containers:      containers,
+		UsedMemory:      n.UsedMemory,
+		UsedCpus:        n.UsedCpus,
+		TotalMemory:     n.TotalMemory,
+		TotalCpus:       n.TotalCpus,
+		HealthIndicator: n.HealthIndicator,
+	}
+}
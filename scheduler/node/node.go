@@ -15,11 +15,18 @@ type Node struct {
 	Labels     map[string]string
 	Containers cluster.Containers
 	Images     []*cluster.Image
+	Networks   cluster.Networks
+	Volumes    cluster.Volumes
 
 	UsedMemory  int64
-	UsedCpus    int64
+	UsedCpus    float64
 	TotalMemory int64
-	TotalCpus   int64
+	TotalCpus   float64
+
+	UsedResources  map[string]int64
+	TotalResources map[string]int64
+
+	Disk cluster.DiskStatus
 
 	HealthIndicator int64
 }
@@ -34,14 +41,37 @@ func NewNode(e *cluster.Engine) *Node {
 		Labels:          e.Labels,
 		Containers:      e.Containers(),
 		Images:          e.Images(),
+		Networks:        e.Networks(),
+		Volumes:         e.Volumes(),
 		UsedMemory:      e.UsedMemory(),
 		UsedCpus:        e.UsedCpus(),
 		TotalMemory:     e.TotalMemory(),
 		TotalCpus:       e.TotalCpus(),
+		UsedResources:   e.UsedResources(),
+		TotalResources:  e.TotalResources(),
+		Disk:            e.DiskStatus(),
 		HealthIndicator: e.HealthIndicator(),
 	}
 }
 
+// Clone returns a copy of the node that can be changed through AddContainer
+// and RemoveContainer without affecting n. Containers, images, networks,
+// volumes, labels and total resources are shared with n and must not be
+// modified in place.
+func (n *Node) Clone() *Node {
+	clone := *n
+	// Cap the slice so that appending to the clone never writes to the
+	// backing array of n.
+	clone.Containers = n.Containers[:len(n.Containers):len(n.Containers)]
+	if n.UsedResources != nil {
+		clone.UsedResources = make(map[string]int64, len(n.UsedResources))
+		for name, amount := range n.UsedResources {
+			clone.UsedResources[name] = amount
+		}
+	}
+	return &clone
+}
+
 // IsHealthy responses if node is in healthy state
 func (n *Node) IsHealthy() bool {
 	return n.HealthIndicator > 0
@@ -56,13 +86,47 @@ func (n *Node) Container(IDOrName string) *cluster.Container {
 func (n *Node) AddContainer(container *cluster.Container) error {
 	if container.Config != nil {
 		memory := container.Config.HostConfig.Memory
-		cpus := container.Config.HostConfig.CPUShares
+		cpus := container.Config.Cpus()
 		if n.TotalMemory-memory < 0 || n.TotalCpus-cpus < 0 {
 			return errors.New("not enough resources")
 		}
+		resources := container.Config.Resources()
+		for name, amount := range resources {
+			if n.TotalResources[name]-amount < 0 {
+				return errors.New("not enough resources")
+			}
+		}
 		n.UsedMemory = n.UsedMemory + memory
 		n.UsedCpus = n.UsedCpus + cpus
+		if len(resources) > 0 && n.UsedResources == nil {
+			n.UsedResources = make(map[string]int64)
+		}
+		for name, amount := range resources {
+			n.UsedResources[name] += amount
+		}
 	}
 	n.Containers = append(n.Containers, container)
 	return nil
 }
+
+// RemoveContainer removes a container from the internal state.
+func (n *Node) RemoveContainer(container *cluster.Container) {
+	for i, c := range n.Containers {
+		if c.ID != container.ID {
+			continue
+		}
+		if container.Config != nil {
+			n.UsedMemory = n.UsedMemory - container.Config.HostConfig.Memory
+			n.UsedCpus = n.UsedCpus - container.Config.Cpus()
+			for name, amount := range container.Config.Resources() {
+				if _, ok := n.UsedResources[name]; ok {
+					n.UsedResources[name] -= amount
+				}
+			}
+		}
+		containers := make(cluster.Containers, 0, len(n.Containers)-1)
+		containers = append(containers, n.Containers[:i]...)
+		n.Containers = append(containers, n.Containers[i+1:]...)
+		return
+	}
+}
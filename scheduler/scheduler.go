@@ -52,7 +52,20 @@ func (s *Scheduler) selectNodesForContainer(nodes []*node.Node, config *cluster.
 		return nil, errNoNodeAvailable
 	}
 
-	return s.strategy.RankAndSort(config, accepted)
+	return s.rank(config, nodes, accepted)
+}
+
+// rank sorts the accepted nodes by order of preference.
+func (s *Scheduler) rank(config *cluster.ContainerConfig, nodes, accepted []*node.Node) ([]*node.Node, error) {
+	ranked, err := s.strategy.RankAndSort(config, accepted)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.SpreadBy() != "" {
+		spreadByLabel(config, nodes, ranked)
+	}
+	return ranked, nil
 }
 
 // Strategy returns the strategy name
@@ -69,3 +82,17 @@ func (s *Scheduler) Filters() string {
 
 	return strings.Join(filters, ", ")
 }
+
+// NodeWarnings returns the warnings of the filters excluding the node
+// regardless of the container.
+func (s *Scheduler) NodeWarnings(n *node.Node) []string {
+	warnings := []string{}
+	for _, f := range s.filters {
+		if warner, ok := f.(filter.NodeWarner); ok {
+			if warning := warner.NodeWarning(n); warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// spreadByLabel reorders the ranked nodes so that the replicas of a group are
+// spread evenly across the values of a node label first, then across the
+// nodes sharing a value. The strategy ranking breaks ties. Replicas are
+// counted on all the nodes of the cluster, including those which were
+// filtered out. Nodes without the label come last.
+func spreadByLabel(config *cluster.ContainerConfig, nodes, ranked []*node.Node) {
+	var (
+		key      = config.SpreadBy()
+		group    = config.Group()
+		perValue = make(map[string]int)
+		perNode  = make(map[string]int, len(nodes))
+	)
+
+	for _, n := range nodes {
+		for _, c := range n.Containers {
+			if c.Config != nil && c.Config.Group() == group {
+				perNode[n.ID]++
+			}
+		}
+		if value, ok := n.Labels[key]; ok {
+			perValue[value] += perNode[n.ID]
+		}
+	}
+
+	sort.Stable(spreadNodes{
+		nodes:    ranked,
+		key:      key,
+		perValue: perValue,
+		perNode:  perNode,
+	})
+}
+
+type spreadNodes struct {
+	nodes    []*node.Node
+	key      string
+	perValue map[string]int
+	perNode  map[string]int
+}
+
+func (s spreadNodes) Len() int      { return len(s.nodes) }
+func (s spreadNodes) Swap(i, j int) { s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i] }
+func (s spreadNodes) Less(i, j int) bool {
+	vi, iok := s.nodes[i].Labels[s.key]
+	vj, jok := s.nodes[j].Labels[s.key]
+	if iok != jok {
+		return iok
+	}
+	if s.perValue[vi] != s.perValue[vj] {
+		return s.perValue[vi] < s.perValue[vj]
+	}
+	return s.perNode[s.nodes[i].ID] < s.perNode[s.nodes[j].ID]
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	networktypes "github.com/docker/engine-api/types/network"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+func createZoneNode(ID, zone string) *node.Node {
+	n := &node.Node{
+		ID:              ID,
+		Name:            ID,
+		TotalMemory:     1024 * 1024 * 1024,
+		TotalCpus:       2,
+		HealthIndicator: 100,
+		Labels:          map[string]string{},
+	}
+	if zone != "" {
+		n.Labels["zone"] = zone
+	}
+	return n
+}
+
+func scheduleReplica(t *testing.T, s *Scheduler, nodes []*node.Node, env ...string) *node.Node {
+	config := cluster.BuildContainerConfig(containertypes.Config{Image: "db", Env: env}, containertypes.HostConfig{}, networktypes.NetworkingConfig{})
+	candidates, err := s.SelectNodesForContainer(nodes, config)
+	assert.NoError(t, err)
+	n := candidates[0]
+	n.AddContainer(&cluster.Container{
+		Container: types.Container{ID: fmt.Sprintf("%s-%d", n.ID, len(n.Containers))},
+		Config:    config,
+	})
+	return n
+}
+
+func TestSpreadByLabel(t *testing.T) {
+	s := &Scheduler{
+		strategy: &strategy.BinpackPlacementStrategy{},
+		filters:  []filter.Filter{&filter.HealthFilter{}},
+	}
+	nodes := []*node.Node{
+		createZoneNode("node-1", "zone-a"),
+		createZoneNode("node-2", "zone-a"),
+		createZoneNode("node-3", "zone-a"),
+		createZoneNode("node-4", "zone-b"),
+		createZoneNode("node-5", ""),
+	}
+
+	// Binpack alone would stack all the replicas on a single node, spread
+	// them across zones first, then across nodes.
+	perZone := map[string]int{}
+	perNode := map[string]int{}
+	for i := 0; i < 4; i++ {
+		n := scheduleReplica(t, s, nodes, "spread:zone")
+		perZone[n.Labels["zone"]]++
+		perNode[n.ID]++
+	}
+	assert.Equal(t, perZone["zone-a"], 2)
+	assert.Equal(t, perZone["zone-b"], 2)
+	assert.Equal(t, perZone[""], 0)
+	assert.Equal(t, perNode["node-4"], 2)
+	for _, ID := range []string{"node-1", "node-2", "node-3"} {
+		assert.True(t, perNode[ID] <= 1)
+	}
+
+	// Replicas of other groups are not taken into account, binpack decides.
+	n := scheduleReplica(t, s, nodes, "spread:zone", "group:other")
+	assert.Equal(t, n.ID, "node-4")
+}
+
+func TestSpreadByLabelFiltered(t *testing.T) {
+	s := &Scheduler{
+		strategy: &strategy.SpreadPlacementStrategy{},
+		filters:  []filter.Filter{&filter.HealthFilter{}},
+	}
+	nodes := []*node.Node{
+		createZoneNode("node-1", "zone-a"),
+		createZoneNode("node-2", "zone-a"),
+		createZoneNode("node-3", "zone-b"),
+	}
+
+	// A replica on an unhealthy node still counts for its zone.
+	scheduleReplica(t, s, nodes[2:], "spread:zone")
+	nodes[2].HealthIndicator = 0
+	n := scheduleReplica(t, s, nodes, "spread:zone")
+	assert.Equal(t, n.Labels["zone"], "zone-a")
+}
@@ -7,8 +7,15 @@ import (
 	"github.com/docker/swarm/scheduler/node"
 )
 
+// for binpack, a healthy node should increase its weight to increase its chance of being selected
+// set healthFactor to 10 to make health degree [0, 100] overpower cpu + memory (each in range [0, 100])
+const binpackHealthFactor int64 = 10
+
 // BinpackPlacementStrategy places a container onto the most packed node in the cluster.
 type BinpackPlacementStrategy struct {
+	// imageLocality is the bonus given to the nodes having the image of the
+	// container, in points per GiB of image.
+	imageLocality int64
 }
 
 // Initialize a BinpackPlacementStrategy.
@@ -23,10 +30,7 @@ func (p *BinpackPlacementStrategy) Name() string {
 
 // RankAndSort sorts nodes based on the binpack strategy applied to the container config.
 func (p *BinpackPlacementStrategy) RankAndSort(config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
-	// for binpack, a healthy node should increase its weight to increase its chance of being selected
-	// set healthFactor to 10 to make health degree [0, 100] overpower cpu + memory (each in range [0, 100])
-	const healthFactor int64 = 10
-	weightedNodes, err := weighNodes(config, nodes, healthFactor)
+	weightedNodes, err := weighNodes(config, nodes, binpackHealthFactor, p.imageLocality)
 	if err != nil {
 		return nil, err
 	}
@@ -38,3 +42,12 @@ func (p *BinpackPlacementStrategy) RankAndSort(config *cluster.ContainerConfig,
 	}
 	return output, nil
 }
+
+// Weigh returns the weight given to each node by the binpack strategy.
+func (p *BinpackPlacementStrategy) Weigh(config *cluster.ContainerConfig, nodes []*node.Node) (map[string]int64, error) {
+	weightedNodes, err := weighNodes(config, nodes, binpackHealthFactor, p.imageLocality)
+	if err != nil {
+		return nil, err
+	}
+	return weightedNodes.weights(), nil
+}
@@ -20,7 +20,7 @@ func createNode(ID string, memory int64, cpus int64) *node.Node {
 		IP:              ID,
 		Addr:            ID,
 		TotalMemory:     memory * 1024 * 1024 * 1024,
-		TotalCpus:       cpus,
+		TotalCpus:       float64(cpus),
 		HealthIndicator: 100,
 	}
 }
@@ -119,19 +119,48 @@ func TestPlaceContainerCPU(t *testing.T) {
 	config := createConfig(0, 1)
 	node1 := selectTopNode(t, s, config, nodes)
 	assert.NoError(t, node1.AddContainer(createContainer("c1", config)))
-	assert.Equal(t, node1.UsedCpus, int64(1))
+	assert.Equal(t, node1.UsedCpus, float64(1))
 
 	// add another container 1CPU
 	config = createConfig(0, 1)
 	node2 := selectTopNode(t, s, config, nodes)
 	assert.NoError(t, node2.AddContainer(createContainer("c2", config)))
-	assert.Equal(t, node2.UsedCpus, int64(2))
+	assert.Equal(t, node2.UsedCpus, float64(2))
 
 	// check that both containers ended on the same node
 	assert.Equal(t, node1.ID, node2.ID)
 	assert.Equal(t, len(node1.Containers), len(node2.Containers))
 }
 
+func TestPlaceContainerFractionalCPU(t *testing.T) {
+	s := &BinpackPlacementStrategy{}
+
+	nodes := []*node.Node{}
+	for i := 0; i < 2; i++ {
+		nodes = append(nodes, createNode(fmt.Sprintf("node-%d", i), 0, 1))
+	}
+
+	// add 4 containers limited to a quarter of a CPU
+	for i := 0; i < 4; i++ {
+		config := cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{
+			Resources: containertypes.Resources{CPUQuota: 25000},
+		}, networktypes.NetworkingConfig{})
+		node := selectTopNode(t, s, config, nodes)
+		assert.NoError(t, node.AddContainer(createContainer(fmt.Sprintf("c%d", i), config)))
+	}
+
+	// they all fit on the same CPU
+	assert.Equal(t, nodes[0].UsedCpus+nodes[1].UsedCpus, float64(1))
+	assert.True(t, len(nodes[0].Containers) == 4 || len(nodes[1].Containers) == 4)
+
+	// the next one doesn't
+	config := cluster.BuildContainerConfig(containertypes.Config{}, containertypes.HostConfig{
+		Resources: containertypes.Resources{CpusetCpus: "0"},
+	}, networktypes.NetworkingConfig{})
+	node := selectTopNode(t, s, config, nodes)
+	assert.Equal(t, node.UsedCpus, float64(0))
+}
+
 func TestPlaceContainerHuge(t *testing.T) {
 	s := &BinpackPlacementStrategy{}
 
@@ -295,3 +324,22 @@ func TestComplexPlacement(t *testing.T) {
 	// check that it ends up on the same node as the 3G
 	assert.Equal(t, node2.ID, node3.ID)
 }
+
+func TestBinpackPreferences(t *testing.T) {
+	s := &BinpackPlacementStrategy{}
+
+	nodes := []*node.Node{
+		createNode("node-0", 4, 4),
+		createNode("node-1", 4, 4),
+	}
+	nodes[0].Labels = map[string]string{"storage": "disk"}
+	nodes[1].Labels = map[string]string{"storage": "ssd"}
+
+	// Binpack prefers the most packed node, unless a preference says otherwise.
+	assert.NoError(t, nodes[0].AddContainer(createContainer("c0", createConfig(1, 1))))
+	config := createConfig(0, 0)
+	assert.Equal(t, selectTopNode(t, s, config, nodes), nodes[0])
+
+	config.AddConstraint("storage==~ssd@weight=1")
+	assert.Equal(t, selectTopNode(t, s, config, nodes), nodes[1])
+}
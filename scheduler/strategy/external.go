@@ -0,0 +1,160 @@
+package strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+const (
+	// ExternalPrefix prefixes the strategy implemented by a remote HTTP
+	// endpoint (ex: --strategy external:http://placement:8080/rank).
+	ExternalPrefix = "external:"
+
+	defaultExternalTimeout = 5 * time.Second
+)
+
+// ExternalPlacementStrategy delegates the ranking of nodes to a remote HTTP
+// endpoint, and falls back to a builtin strategy when the endpoint fails.
+type ExternalPlacementStrategy struct {
+	url      string
+	client   *http.Client
+	fallback PlacementStrategy
+}
+
+// ExternalStrategyRequest is the body POSTed to the endpoint of the external
+// strategy.
+type ExternalStrategyRequest struct {
+	Config *cluster.ContainerConfig
+	Nodes  []*node.Description
+}
+
+// ExternalStrategyResponse is the answer expected from the endpoint of the
+// external strategy.
+type ExternalStrategyResponse struct {
+	// Nodes holds the IDs of the nodes by order of preference. Nodes left
+	// out are not used for the container.
+	Nodes []string
+}
+
+// NewExternalPlacementStrategy creates an external strategy from its
+// specification, in the form <url>[,timeout=<duration>][,fallback=<strategy>].
+// By default, requests time out after 5s and fall back to spread.
+func NewExternalPlacementStrategy(spec string) (*ExternalPlacementStrategy, error) {
+	options := strings.Split(spec, ",")
+	p := &ExternalPlacementStrategy{
+		url:    options[0],
+		client: &http.Client{Timeout: defaultExternalTimeout},
+	}
+	if u, err := url.Parse(p.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid URL %q for the external strategy", p.url)
+	}
+
+	fallback := "spread"
+	for _, option := range options[1:] {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid option %q for the external strategy", option)
+		}
+		switch kv[0] {
+		case "timeout":
+			timeout, err := time.ParseDuration(kv[1])
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid timeout %q for the external strategy", kv[1])
+			}
+			p.client.Timeout = timeout
+		case "fallback":
+			fallback = kv[1]
+		default:
+			return nil, fmt.Errorf("unknown option %q for the external strategy", kv[0])
+		}
+	}
+
+	for _, strategy := range strategies {
+		if strategy.Name() == fallback {
+			p.fallback = strategy
+		}
+	}
+	if p.fallback == nil {
+		return nil, fmt.Errorf("invalid fallback %q for the external strategy, expected one of %s", fallback, strings.Join(List(), ", "))
+	}
+	return p, nil
+}
+
+// Initialize an ExternalPlacementStrategy.
+func (p *ExternalPlacementStrategy) Initialize() error {
+	return p.fallback.Initialize()
+}
+
+// Name returns the name of the strategy.
+func (p *ExternalPlacementStrategy) Name() string {
+	return "external"
+}
+
+// RankAndSort sorts nodes in the order returned by the endpoint. The fallback
+// strategy ranks the nodes when the endpoint fails.
+func (p *ExternalPlacementStrategy) RankAndSort(config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
+	response, err := p.call(config, nodes)
+	if err != nil {
+		log.WithFields(log.Fields{"url": p.url, "fallback": p.fallback.Name()}).WithError(err).Warn("External strategy failed, falling back")
+		return p.fallback.RankAndSort(config, nodes)
+	}
+
+	byID := make(map[string]*node.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	output := make([]*node.Node, 0, len(response.Nodes))
+	for _, ID := range response.Nodes {
+		if n, ok := byID[ID]; ok {
+			output = append(output, n)
+			// Ignore duplicates.
+			delete(byID, ID)
+		}
+	}
+
+	if len(output) == 0 {
+		return nil, ErrNoResourcesAvailable
+	}
+	return output, nil
+}
+
+func (p *ExternalPlacementStrategy) call(config *cluster.ContainerConfig, nodes []*node.Node) (*ExternalStrategyResponse, error) {
+	request := ExternalStrategyRequest{
+		Config: config,
+		Nodes:  make([]*node.Description, 0, len(nodes)),
+	}
+	for _, n := range nodes {
+		request.Nodes = append(request.Nodes, n.Describe())
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	response := &ExternalStrategyResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExternalPlacementStrategy(t *testing.T) {
+	p, err := NewExternalPlacementStrategy("http://placement:8080/rank")
+	assert.NoError(t, err)
+	assert.Equal(t, p.Name(), "external")
+	assert.Equal(t, p.url, "http://placement:8080/rank")
+	assert.Equal(t, p.client.Timeout, defaultExternalTimeout)
+	assert.Equal(t, p.fallback.Name(), "spread")
+
+	p, err = NewExternalPlacementStrategy("https://placement/rank,timeout=500ms,fallback=binpack")
+	assert.NoError(t, err)
+	assert.Equal(t, p.client.Timeout, 500*time.Millisecond)
+	assert.Equal(t, p.fallback.Name(), "binpack")
+
+	for _, spec := range []string{
+		"",
+		"placement:8080",
+		"http://placement,timeout=forever",
+		"http://placement,fallback=external",
+		"http://placement,retries=3",
+	} {
+		_, err = NewExternalPlacementStrategy(spec)
+		assert.Error(t, err, spec)
+	}
+
+	s, err := New(ExternalPrefix + "http://placement")
+	assert.NoError(t, err)
+	assert.Equal(t, s.Name(), "external")
+}
+
+func TestExternalPlacementStrategy(t *testing.T) {
+	var request ExternalStrategyRequest
+	var ranking []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = ExternalStrategyRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		json.NewEncoder(w).Encode(ExternalStrategyResponse{Nodes: ranking})
+	}))
+	defer server.Close()
+
+	p, err := NewExternalPlacementStrategy(server.URL)
+	assert.NoError(t, err)
+
+	nodes := []*node.Node{
+		createNode("node-0", 2, 2),
+		createNode("node-1", 2, 2),
+		createNode("node-2", 2, 2),
+	}
+	config := createConfig(1, 0)
+	assert.NoError(t, nodes[1].AddContainer(createContainer("c1", config)))
+
+	// Nodes left out, unknown nodes and duplicates are ignored.
+	ranking = []string{"node-2", "node-3", "node-0", "node-2"}
+	ranked, err := p.RankAndSort(config, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, ranked, []*node.Node{nodes[2], nodes[0]})
+
+	assert.Len(t, request.Nodes, 3)
+	assert.Equal(t, request.Nodes[1].ID, "node-1")
+	assert.Equal(t, request.Nodes[1].UsedMemory, int64(1024*1024*1024))
+	assert.Len(t, request.Nodes[1].Containers, 1)
+
+	// The endpoint may reject every node.
+	ranking = []string{}
+	_, err = p.RankAndSort(config, nodes)
+	assert.Equal(t, err, ErrNoResourcesAvailable)
+}
+
+func TestExternalPlacementStrategyFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	nodes := []*node.Node{
+		createNode("node-0", 2, 2),
+		createNode("node-1", 2, 2),
+	}
+	config := createConfig(1, 0)
+	assert.NoError(t, nodes[0].AddContainer(createContainer("c1", config)))
+
+	// spread prefers the empty node.
+	p, err := NewExternalPlacementStrategy(server.URL + ",timeout=50ms")
+	assert.NoError(t, err)
+	ranked, err := p.RankAndSort(config, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, ranked[0].ID, "node-1")
+
+	// binpack prefers the used node.
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer errServer.Close()
+	p, err = NewExternalPlacementStrategy(errServer.URL + ",fallback=binpack")
+	assert.NoError(t, err)
+	ranked, err = p.RankAndSort(config, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, ranked[0].ID, "node-0")
+}
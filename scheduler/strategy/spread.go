@@ -7,8 +7,15 @@ import (
 	"github.com/docker/swarm/scheduler/node"
 )
 
+// for spread, a healthy node should decrease its weight to increase its chance of being selected
+// set healthFactor to -10 to make health degree [0, 100] overpower cpu + memory (each in range [0, 100])
+const spreadHealthFactor int64 = -10
+
 // SpreadPlacementStrategy places a container on the node with the fewest running containers.
 type SpreadPlacementStrategy struct {
+	// imageLocality is the bonus given to the nodes having the image of the
+	// container, in points per GiB of image.
+	imageLocality int64
 }
 
 // Initialize a SpreadPlacementStrategy.
@@ -23,10 +30,7 @@ func (p *SpreadPlacementStrategy) Name() string {
 
 // RankAndSort sorts nodes based on the spread strategy applied to the container config.
 func (p *SpreadPlacementStrategy) RankAndSort(config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
-	// for spread, a healthy node should decrease its weight to increase its chance of being selected
-	// set healthFactor to -10 to make health degree [0, 100] overpower cpu + memory (each in range [0, 100])
-	const healthFactor int64 = -10
-	weightedNodes, err := weighNodes(config, nodes, healthFactor)
+	weightedNodes, err := weighNodes(config, nodes, spreadHealthFactor, p.imageLocality)
 	if err != nil {
 		return nil, err
 	}
@@ -38,3 +42,12 @@ func (p *SpreadPlacementStrategy) RankAndSort(config *cluster.ContainerConfig, n
 	}
 	return output, nil
 }
+
+// Weigh returns the weight given to each node by the spread strategy.
+func (p *SpreadPlacementStrategy) Weigh(config *cluster.ContainerConfig, nodes []*node.Node) (map[string]int64, error) {
+	weightedNodes, err := weighNodes(config, nodes, spreadHealthFactor, p.imageLocality)
+	if err != nil {
+		return nil, err
+	}
+	return weightedNodes.weights(), nil
+}
@@ -114,13 +114,13 @@ func TestSpreadPlaceContainerCPU(t *testing.T) {
 	config := createConfig(0, 1)
 	node1 := selectTopNode(t, s, config, nodes)
 	assert.NoError(t, node1.AddContainer(createContainer("c1", config)))
-	assert.Equal(t, node1.UsedCpus, int64(1))
+	assert.Equal(t, node1.UsedCpus, float64(1))
 
 	// add another container 1CPU
 	config = createConfig(0, 1)
 	node2 := selectTopNode(t, s, config, nodes)
 	assert.NoError(t, node2.AddContainer(createContainer("c2", config)))
-	assert.Equal(t, node2.UsedCpus, int64(1))
+	assert.Equal(t, node2.UsedCpus, float64(1))
 
 	// check that both containers ended on different node
 	assert.NotEqual(t, node1.ID, node2.ID)
@@ -213,3 +213,33 @@ func TestSpreadComplexPlacement(t *testing.T) {
 	// check that it ends up on the same node as the 2G
 	assert.Equal(t, node1.ID, node3.ID)
 }
+
+func TestSpreadPreferences(t *testing.T) {
+	s := &SpreadPlacementStrategy{}
+
+	nodes := []*node.Node{
+		createNode("node-0", 4, 4),
+		createNode("node-1", 4, 4),
+		createNode("node-2", 4, 4),
+	}
+	nodes[0].Labels = map[string]string{"storage": "disk", "zone": "a"}
+	nodes[1].Labels = map[string]string{"storage": "ssd", "zone": "b"}
+	nodes[2].Labels = map[string]string{"storage": "ssd", "zone": "a"}
+
+	// node-2 satisfies both preferences, even though it is the busiest node.
+	assert.NoError(t, nodes[2].AddContainer(createContainer("c0", createConfig(1, 1))))
+	config := createConfig(0, 0)
+	config.AddConstraint("storage==~ssd@weight=2")
+	config.AddConstraint("zone==~a@weight=1")
+	assert.Equal(t, selectTopNode(t, s, config, nodes), nodes[2])
+
+	// The heaviest preference wins.
+	config = createConfig(0, 0)
+	config.AddConstraint("storage==~ssd@weight=1")
+	config.AddConstraint("zone==~a@weight=2")
+	assert.Equal(t, selectTopNode(t, s, config, nodes), nodes[2])
+
+	config = createConfig(0, 0)
+	config.AddConstraint("storage==~disk@weight=1")
+	assert.Equal(t, selectTopNode(t, s, config, nodes), nodes[0])
+}
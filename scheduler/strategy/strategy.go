@@ -2,6 +2,9 @@ package strategy
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
@@ -23,6 +26,14 @@ type PlacementStrategy interface {
 	RankAndSort(config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error)
 }
 
+// WeightedStrategy is implemented by the strategies which rank nodes by
+// weight. It is used to explain scheduling decisions.
+type WeightedStrategy interface {
+	// Weigh returns the weight of each node able to run the container,
+	// indexed by node ID.
+	Weigh(config *cluster.ContainerConfig, nodes []*node.Node) (map[string]int64, error)
+}
+
 var (
 	strategies []PlacementStrategy
 	// ErrNotSupported is the error returned when a strategy name does not match
@@ -48,6 +59,24 @@ func New(name string) (PlacementStrategy, error) {
 		name = "binpack"
 	}
 
+	if strings.HasPrefix(name, ExternalPrefix) {
+		strategy, err := NewExternalPlacementStrategy(strings.TrimPrefix(name, ExternalPrefix))
+		if err != nil {
+			return nil, err
+		}
+		log.WithField("name", strategy.Name()).Debugf("Initializing strategy")
+		return strategy, strategy.Initialize()
+	}
+
+	if options := strings.Split(name, ","); len(options) > 1 {
+		strategy, err := newWeightedStrategy(options[0], options[1:])
+		if err != nil {
+			return nil, err
+		}
+		log.WithField("name", strategy.Name()).Debugf("Initializing strategy")
+		return strategy, strategy.Initialize()
+	}
+
 	for _, strategy := range strategies {
 		if strategy.Name() == name {
 			log.WithField("name", name).Debugf("Initializing strategy")
@@ -59,6 +88,36 @@ func New(name string) (PlacementStrategy, error) {
 	return nil, ErrNotSupported
 }
 
+// newWeightedStrategy creates a spread or binpack strategy with options in
+// the form key=value (ex. imagelocality=25).
+func newWeightedStrategy(name string, options []string) (PlacementStrategy, error) {
+	var imageLocality int64
+	for _, option := range options {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid option %q for the %s strategy", option, name)
+		}
+		switch kv[0] {
+		case "imagelocality":
+			value, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("invalid image locality %q for the %s strategy", kv[1], name)
+			}
+			imageLocality = value
+		default:
+			return nil, fmt.Errorf("unknown option %q for the %s strategy", kv[0], name)
+		}
+	}
+
+	switch name {
+	case "spread":
+		return &SpreadPlacementStrategy{imageLocality: imageLocality}, nil
+	case "binpack", "binpacking":
+		return &BinpackPlacementStrategy{imageLocality: imageLocality}, nil
+	}
+	return nil, fmt.Errorf("the %s strategy doesn't support options", name)
+}
+
 // List returns the names of all the available strategies.
 func List() []string {
 	names := []string{}
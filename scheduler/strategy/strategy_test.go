@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	s, err := New("spread,imagelocality=25")
+	assert.NoError(t, err)
+	assert.Equal(t, s, &SpreadPlacementStrategy{imageLocality: 25})
+
+	s, err = New("binpack,imagelocality=10")
+	assert.NoError(t, err)
+	assert.Equal(t, s, &BinpackPlacementStrategy{imageLocality: 10})
+
+	// The registered strategies are left untouched.
+	s, err = New("spread")
+	assert.NoError(t, err)
+	assert.Equal(t, s, &SpreadPlacementStrategy{})
+
+	for _, name := range []string{"spread,imagelocality=-1", "spread,imagelocality", "spread,foo=1", "random,imagelocality=1", "unknown,imagelocality=1"} {
+		_, err = New(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestImageLocality(t *testing.T) {
+	const GiB = 1 << 30
+
+	for _, s := range []PlacementStrategy{
+		&SpreadPlacementStrategy{imageLocality: 25},
+		&BinpackPlacementStrategy{imageLocality: 25},
+	} {
+		nodes := []*node.Node{
+			createNode("node-0", 4, 4),
+			createNode("node-1", 4, 4),
+			createNode("node-with-image", 4, 4),
+		}
+		nodes[2].Images = []*cluster.Image{{Image: types.Image{
+			ID:       "sha256:ml",
+			RepoTags: []string{"ml:latest"},
+			Size:     4 * GiB,
+		}}}
+
+		config := createConfig(1, 1)
+		config.Image = "ml:latest"
+		assert.Equal(t, selectTopNode(t, s, config, nodes).ID, "node-with-image", s.Name())
+
+		// Other images get no bonus.
+		config.Image = "nginx"
+		weights, err := s.(WeightedStrategy).Weigh(config, nodes)
+		assert.NoError(t, err)
+		assert.Equal(t, weights["node-0"], weights["node-with-image"], s.Name())
+	}
+
+	n := createNode("node", 4, 4)
+	n.Images = []*cluster.Image{
+		{Image: types.Image{ID: "sha256:small", RepoTags: []string{"small:latest"}, Size: GiB / 2}},
+		{Image: types.Image{ID: "sha256:huge", RepoTags: []string{"huge:latest"}, Size: 100 * GiB}},
+	}
+	config := createConfig(0, 0)
+	config.Image = "small:latest"
+	assert.Equal(t, imageLocalityBonus(config, n, 25), int64(12))
+	assert.Equal(t, imageLocalityBonus(config, n, 0), int64(0))
+	config.Image = "huge"
+	assert.Equal(t, imageLocalityBonus(config, n, 25), maxImageLocalityBonus)
+}
@@ -2,9 +2,19 @@ package strategy
 
 import (
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
 	"github.com/docker/swarm/scheduler/node"
 )
 
+// preferenceFactor is the weight given to each unit of preference satisfied by
+// a node. It makes a single preference overpower cpu + memory (each in range
+// [0, 100]) when nodes are otherwise equally used.
+const preferenceFactor int64 = 100
+
+// maxImageLocalityBonus caps the bonus given to a node having the image of the
+// container, so that it never overpowers more than a single preference.
+const maxImageLocalityBonus int64 = preferenceFactor
+
 // WeightedNode represents a node in the cluster with a given weight, typically used for sorting
 // purposes.
 type weightedNode struct {
@@ -36,15 +46,26 @@ func (n weightedNodeList) Less(i, j int) bool {
 	return ip.Weight < jp.Weight
 }
 
-func weighNodes(config *cluster.ContainerConfig, nodes []*node.Node, healthinessFactor int64) (weightedNodeList, error) {
+// weighNodes weighs the nodes able to run the container. The sign of
+// healthinessFactor tells whether a higher weight makes a node more likely to
+// be selected, and is also applied to the preferences satisfied by the node
+// and to the image locality bonus, given in points per GiB of image.
+func weighNodes(config *cluster.ContainerConfig, nodes []*node.Node, healthinessFactor, imageLocality int64) (weightedNodeList, error) {
 	weightedNodes := weightedNodeList{}
 
+	preferenceSign := int64(1)
+	if healthinessFactor < 0 {
+		preferenceSign = -1
+	}
+	preferences := filter.PreferenceScores(config, nodes)
+
+	cpus := config.Cpus()
 	for _, node := range nodes {
 		nodeMemory := node.TotalMemory
 		nodeCpus := node.TotalCpus
 
 		// Skip nodes that are smaller than the requested resources.
-		if nodeMemory < int64(config.HostConfig.Memory) || nodeCpus < config.HostConfig.CPUShares {
+		if nodeMemory < int64(config.HostConfig.Memory) || nodeCpus < cpus {
 			continue
 		}
 
@@ -53,15 +74,17 @@ func weighNodes(config *cluster.ContainerConfig, nodes []*node.Node, healthiness
 			memoryScore int64 = 100
 		)
 
-		if config.HostConfig.CPUShares > 0 {
-			cpuScore = (node.UsedCpus + config.HostConfig.CPUShares) * 100 / nodeCpus
+		if cpus > 0 {
+			cpuScore = int64((node.UsedCpus + cpus) * 100 / nodeCpus)
 		}
 		if config.HostConfig.Memory > 0 {
 			memoryScore = (node.UsedMemory + config.HostConfig.Memory) * 100 / nodeMemory
 		}
 
 		if cpuScore <= 100 && memoryScore <= 100 {
-			weightedNodes = append(weightedNodes, &weightedNode{Node: node, Weight: cpuScore + memoryScore + healthinessFactor*node.HealthIndicator})
+			weight := cpuScore + memoryScore + healthinessFactor*node.HealthIndicator + preferenceSign*preferenceFactor*preferences[node.ID]
+			weight += preferenceSign * imageLocalityBonus(config, node, imageLocality)
+			weightedNodes = append(weightedNodes, &weightedNode{Node: node, Weight: weight})
 		}
 	}
 
@@ -71,3 +94,30 @@ func weighNodes(config *cluster.ContainerConfig, nodes []*node.Node, healthiness
 
 	return weightedNodes, nil
 }
+
+// imageLocalityBonus returns the bonus of a node already having the image of
+// the container, proportional to the size of the image: pulling large images
+// on another node is slow.
+func imageLocalityBonus(config *cluster.ContainerConfig, n *node.Node, imageLocality int64) int64 {
+	if imageLocality <= 0 || config.Image == "" {
+		return 0
+	}
+	for _, image := range n.Images {
+		if image.Match(config.Image, true) {
+			bonus := imageLocality * image.Size / (1 << 30)
+			if bonus > maxImageLocalityBonus {
+				return maxImageLocalityBonus
+			}
+			return bonus
+		}
+	}
+	return 0
+}
+
+func (n weightedNodeList) weights() map[string]int64 {
+	weights := make(map[string]int64, len(n))
+	for _, wn := range n {
+		weights[wn.Node.ID] = wn.Weight
+	}
+	return weights
+}
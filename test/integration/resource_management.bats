@@ -119,3 +119,22 @@ function teardown() {
 	[[ "${output}" == *"Reserved CPUs: 2"* ]]
 	[[ "${output}" == *"Reserved CPUs: 0"* ]]
 }
+
+@test "resource limitation: countable resources" {
+	start_docker_with_busybox 1 --label resource.gpu=2
+	start_docker_with_busybox 1
+	swarm_manage
+
+	docker_swarm run --name container_test1 -e resource:gpu=1 busybox sh
+	docker_swarm run --name container_test2 --label 'com.docker.swarm.resources={"gpu":1}' busybox sh
+	run docker_swarm info
+	[ "$status" -eq 0 ]
+	[[ "${output}" == *"Reserved Resources: gpu: 2 / 2"* ]]
+
+	run docker_swarm run -e resource:gpu=1 busybox sh
+	[ "$status" -ne 0 ]
+	[[ "${output}" == *"unable to find a node with enough free resources"* ]]
+
+	docker_swarm rm container_test1
+	docker_swarm run -e resource:gpu=1 busybox sh
+}